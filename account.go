@@ -1,46 +1,105 @@
 package neato
 
 import (
+	"context"
 	"fmt"
+	"time"
 )
 
+// NewAccount returns an account that caches responses according to
+// DefaultCacheConfig.
 func NewAccount(session *PasswordSession) *Account {
+	return NewAccountWithCache(session, NewCache(DefaultCacheConfig))
+}
+
+// NewAccountWithCache returns an account that caches responses in the given
+// cache. A nil cache disables caching.
+func NewAccountWithCache(session *PasswordSession, cache *Cache) *Account {
 	return &Account{
 		session: session,
+		cache:   cache,
 	}
 }
 
+// Account is safe for concurrent use by multiple goroutines, and so are the
+// robots, maps and states it returns. Concurrent identical requests share a
+// single round-trip through the cache.
 type Account struct {
 	session *PasswordSession
-	robots  []*Robot
+	cache   *Cache
 }
 
+// Robots returns the robots of the account. The result is cached for
+// CacheConfig.RobotsTTL, and the robots share the cache of the account.
 func (a *Account) Robots() ([]*Robot, error) {
-	if a.robots != nil {
-		return a.robots, nil
+	return a.RobotsContext(context.Background())
+}
+
+// RobotsContext is like Robots, but gives up waiting for the robots when ctx
+// is done. Robots are only cached in memory, never in CacheConfig.Dir,
+// because they carry their secret key.
+func (a *Account) RobotsContext(ctx context.Context) ([]*Robot, error) {
+	fetch := func(ctx context.Context) ([]*Robot, error) {
+		var resp []*Robot
+		if err := a.session.get(ctx, "users/me/robots", &resp); err != nil {
+			return nil, fmt.Errorf("failed to fetch robots: %w", err)
+		}
+		return resp, nil
 	}
-	var resp []*Robot
-	if err := a.session.get("users/me/robots", &resp); err != nil {
-		return nil, fmt.Errorf("failed to fetch robots: %w", err)
+	prepare := func(robots []*Robot, _ time.Time) {
+		for _, r := range robots {
+			r.session = a.session
+			r.cache = a.cache
+		}
+	}
+	return cached(ctx, a.cache, cacheKeyRobots(a.session.cacheScope()), a.cache.config().RobotsTTL, fetch, prepare)
+}
+
+// Cache returns the cache of the account, which may be nil.
+func (a *Account) Cache() *Cache {
+	return a.cache
+}
+
+// InvalidateCache drops every cached response of the account and its robots.
+func (a *Account) InvalidateCache() {
+	a.cache.Clear()
+}
+
+// RobotBySerial returns the robot with the given serial number.
+func (a *Account) RobotBySerial(serial string) (*Robot, error) {
+	robots, err := a.Robots()
+	if err != nil {
+		return nil, err
 	}
-	for _, r := range resp {
-		r.session = a.session
+	for _, r := range robots {
+		if r.Serial == serial {
+			return r, nil
+		}
 	}
-	return resp, nil
+	return nil, fmt.Errorf("robot with serial '%s' not found", serial)
 }
 
+// Maps returns the maps of all the robots, fetched concurrently, in robot
+// order. If any robot fails, the maps of the others are returned together
+// with an error.
 func (a *Account) Maps() ([]*Map, error) {
 	robots, err := a.Robots()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get robots")
+		return nil, fmt.Errorf("failed to get robots: %w", err)
+	}
+	results, err := a.MapsByRobot(context.Background(), DefaultConcurrency)
+	if err != nil {
+		return nil, err
 	}
 	allMaps := make([]*Map, 0)
+	errs := make(map[string]error)
 	for _, robot := range robots {
-		maps, err := robot.Maps()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get maps for robot '%s': %w", robot.Serial, err)
+		res := results[robot.Serial]
+		if res.Err != nil {
+			errs[robot.Serial] = fmt.Errorf("failed to get maps: %w", res.Err)
+			continue
 		}
-		allMaps = append(allMaps, maps...)
+		allMaps = append(allMaps, res.Maps...)
 	}
-	return allMaps, nil
+	return allMaps, joinRobotErrors(errs)
 }
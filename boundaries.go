@@ -0,0 +1,118 @@
+package neato
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// PersistentMap is a floor plan saved by the robot, over which zones and
+// no-go lines can be defined.
+type PersistentMap struct {
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	URL                string `json:"url"`
+	URLValidForSeconds *int   `json:"url_valid_for_seconds"`
+	RawFloorMapURL     string `json:"raw_floor_map_url"`
+}
+
+func (p *PersistentMap) String() string {
+	return fmt.Sprintf("ID: '%s', Name: '%s'", p.ID, p.Name)
+}
+
+// Download writes the persistent map image to w.
+func (p *PersistentMap) Download(ctx context.Context, w io.Writer) error {
+	if err := httpDownload(ctx, p.URL, w); err != nil {
+		return fmt.Errorf("failed to download persistent map '%s': %w", p.ID, err)
+	}
+	return nil
+}
+
+func (r *Robot) PersistentMaps() ([]*PersistentMap, error) {
+	var resp []*PersistentMap
+	if err := r.session.get(context.Background(), "users/me/robots/"+r.Serial+"/persistent_maps", &resp); err != nil {
+		return nil, fmt.Errorf("failed to get persistent maps: %w", err)
+	}
+	return resp, nil
+}
+
+type BoundaryType string
+
+var (
+	// BoundaryTypePolygon is a zone that can be cleaned on its own.
+	BoundaryTypePolygon BoundaryType = "polygon"
+	// BoundaryTypePolyline is a no-go line.
+	BoundaryTypePolyline BoundaryType = "polyline"
+)
+
+// Boundary is a zone or a no-go line of a persistent map. Vertices are
+// normalized to the size of the persistent map image, with (0, 0) at the top
+// left corner and (1, 1) at the bottom right one.
+type Boundary struct {
+	ID       string       `json:"id" yaml:"id"`
+	Type     BoundaryType `json:"type" yaml:"type"`
+	Name     string       `json:"name" yaml:"name"`
+	Color    string       `json:"color" yaml:"color"`
+	Enabled  bool         `json:"enabled" yaml:"enabled"`
+	Vertices [][2]float64 `json:"vertices" yaml:"vertices"`
+}
+
+func (b *Boundary) String() string {
+	return fmt.Sprintf("ID: '%s', Type: %s, Name: '%s', Vertices: %d", b.ID, b.Type, b.Name, len(b.Vertices))
+}
+
+type GetMapBoundariesCommand struct {
+	MapID string
+}
+
+func (c *GetMapBoundariesCommand) Name() string { return "getMapBoundaries" }
+func (c *GetMapBoundariesCommand) Params() interface{} {
+	return map[string]string{"mapId": c.MapID}
+}
+
+type SetMapBoundariesCommand struct {
+	MapID      string
+	Boundaries []*Boundary
+}
+
+func (c *SetMapBoundariesCommand) Name() string { return "setMapBoundaries" }
+func (c *SetMapBoundariesCommand) Params() interface{} {
+	return map[string]interface{}{
+		"mapId":      c.MapID,
+		"boundaries": c.Boundaries,
+	}
+}
+
+// MapBoundaries returns the zones and no-go lines of a persistent map.
+func (r *Robot) MapBoundaries(ctx context.Context, mapID string) ([]*Boundary, error) {
+	resp, err := r.Do(ctx, &GetMapBoundariesCommand{MapID: mapID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get map boundaries: %w", err)
+	}
+	var data struct {
+		MapID      string      `json:"mapId"`
+		Boundaries []*Boundary `json:"boundaries"`
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode map boundaries: %w", err)
+	}
+	return data.Boundaries, nil
+}
+
+// SetMapBoundaries replaces the zones and no-go lines of a persistent map.
+// The boundaries are validated against the robot's limits first, and
+// nothing is sent if ValidateBoundaries reports errors.
+func (r *Robot) SetMapBoundaries(ctx context.Context, mapID string, boundaries []*Boundary) error {
+	caps, err := r.Capabilities(ctx)
+	if err != nil {
+		return err
+	}
+	if problems := ValidateBoundaries(boundaries, caps.BoundaryLimits()); problems.HasErrors() {
+		return fmt.Errorf("invalid map boundaries: %w", problems)
+	}
+	if _, err := r.Do(ctx, &SetMapBoundariesCommand{MapID: mapID, Boundaries: boundaries}); err != nil {
+		return fmt.Errorf("failed to set map boundaries: %w", err)
+	}
+	return nil
+}
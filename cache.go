@@ -0,0 +1,305 @@
+package neato
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheConfig holds the time-to-live of each cached resource. A TTL of zero
+// or less disables caching for that resource, although concurrent identical
+// requests are still deduplicated.
+type CacheConfig struct {
+	RobotsTTL time.Duration
+	MapsTTL   time.Duration
+	StateTTL  time.Duration
+	// Dir, if not empty, is a directory where cached resources are also
+	// stored, so that they survive across processes. Robots are never
+	// stored there, because they carry their secret key.
+	Dir string
+}
+
+// DefaultCacheConfig is the configuration used by NewAccount.
+var DefaultCacheConfig = CacheConfig{
+	RobotsTTL: time.Hour,
+	MapsTTL:   10 * time.Minute,
+	StateTTL:  0,
+}
+
+// Cache stores the responses of the Neato cloud and of the robots for a
+// configurable amount of time. Concurrent requests for the same resource are
+// merged into a single round-trip. A nil *Cache is valid and caches nothing.
+//
+// Cache is safe for concurrent use by multiple goroutines, and by multiple
+// processes sharing the same Dir. Config must not be modified once the cache
+// is in use.
+type Cache struct {
+	Config CacheConfig
+
+	mu      sync.Mutex
+	gen     uint64
+	entries map[string]*cacheEntry
+	calls   map[string]*cacheCall
+}
+
+type cacheEntry struct {
+	value     interface{}
+	fetchedAt time.Time
+	ttl       time.Duration
+}
+
+func (e *cacheEntry) fresh(now time.Time) bool {
+	return e.ttl > 0 && now.Before(e.fetchedAt.Add(e.ttl))
+}
+
+type cacheCall struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// cacheFile is the on-disk representation of a cache entry.
+type cacheFile struct {
+	FetchedAt time.Time       `json:"fetched_at"`
+	Value     json.RawMessage `json:"value"`
+}
+
+// NewCache returns a new cache with the given configuration.
+func NewCache(config CacheConfig) *Cache {
+	return &Cache{
+		Config:  config,
+		entries: make(map[string]*cacheEntry),
+		calls:   make(map[string]*cacheCall),
+	}
+}
+
+// config returns the configuration of the cache, or the zero configuration
+// if c is nil.
+func (c *Cache) config() CacheConfig {
+	if c == nil {
+		return CacheConfig{}
+	}
+	return c.Config
+}
+
+// Clear removes every entry, both from memory and from disk. Requests that
+// are in flight when Clear is called do not populate the cache.
+func (c *Cache) Clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.entries = nil
+	if c.Config.Dir == "" {
+		return
+	}
+	files, err := filepath.Glob(filepath.Join(c.Config.Dir, "*.json"))
+	if err != nil {
+		return
+	}
+	for _, f := range files {
+		_ = os.Remove(f)
+	}
+}
+
+// invalidate removes the given keys, both from memory and from disk.
+func (c *Cache) invalidate(keys ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for _, key := range keys {
+		delete(c.entries, key)
+		if c.Config.Dir != "" {
+			_ = os.Remove(c.path(key))
+		}
+	}
+}
+
+// cacheFileName maps a cache key to a file name, replacing any character that
+// is not safe in a path.
+func cacheFileName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Config.Dir, cacheFileName(key)+".json")
+}
+
+// onDisk reports whether the entry for key may be stored in Config.Dir.
+// Robots carry the secret key used to sign Nucleo requests, so they are only
+// cached in memory.
+func (c *Cache) onDisk(key string) bool {
+	return c.Config.Dir != "" && !strings.HasPrefix(key, cacheKeyRobots(""))
+}
+
+// load reads a fresh entry from disk into v.
+func (c *Cache) load(key string, ttl time.Duration, v interface{}) (time.Time, bool) {
+	if !c.onDisk(key) || ttl <= 0 {
+		return time.Time{}, false
+	}
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return time.Time{}, false
+	}
+	var f cacheFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return time.Time{}, false
+	}
+	if !time.Now().Before(f.FetchedAt.Add(ttl)) {
+		return time.Time{}, false
+	}
+	if err := json.Unmarshal(f.Value, v); err != nil {
+		return time.Time{}, false
+	}
+	return f.FetchedAt, true
+}
+
+// store writes an entry to disk. The file is written to a temporary location
+// first, so that concurrent processes never read a partial entry.
+func (c *Cache) store(key string, fetchedAt time.Time, v interface{}) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cacheFile{FetchedAt: fetchedAt, Value: value})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Config.Dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.Config.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to store cache entry '%s': %w", key, err)
+	}
+	return nil
+}
+
+// cacheFetchTimeout bounds a fetch started by cached, since it is not
+// canceled with the context of its callers.
+const cacheFetchTimeout = time.Minute
+
+// detachedContext carries the values of its parent but is never canceled,
+// so that a fetch shared by several callers outlives the caller that
+// started it.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// cached returns the value stored under key if it is younger than ttl,
+// otherwise it calls fetch. Concurrent calls for the same key share a single
+// fetch. prepare, if not nil, is called once on every value that enters the
+// cache, either from fetch or from disk, with the time it was fetched at.
+//
+// The shared fetch runs with a context detached from ctx and bounded by
+// cacheFetchTimeout, while every caller stops waiting for it when its own ctx
+// is done.
+func cached[T any](ctx context.Context, c *Cache, key string, ttl time.Duration, fetch func(context.Context) (T, error), prepare func(T, time.Time)) (T, error) {
+	if c == nil {
+		v, err := fetch(ctx)
+		if err == nil && prepare != nil {
+			prepare(v, time.Now())
+		}
+		return v, err
+	}
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && e.fresh(time.Now()) {
+		c.mu.Unlock()
+		return e.value.(T), nil
+	}
+	call, ok := c.calls[key]
+	if !ok {
+		call = &cacheCall{done: make(chan struct{})}
+		if c.calls == nil {
+			c.calls = make(map[string]*cacheCall)
+		}
+		c.calls[key] = call
+		gen := c.gen
+		go func() {
+			fetchCtx, cancel := context.WithTimeout(detachedContext{ctx}, cacheFetchTimeout)
+			defer cancel()
+			var v T
+			fetchedAt, fromDisk := c.load(key, ttl, &v)
+			var err error
+			if !fromDisk {
+				fetchedAt = time.Now()
+				v, err = fetch(fetchCtx)
+			}
+			if err == nil && prepare != nil {
+				prepare(v, fetchedAt)
+			}
+
+			c.mu.Lock()
+			if err == nil && ttl > 0 && gen == c.gen {
+				if c.entries == nil {
+					c.entries = make(map[string]*cacheEntry)
+				}
+				c.entries[key] = &cacheEntry{value: v, fetchedAt: fetchedAt, ttl: ttl}
+				if !fromDisk && c.onDisk(key) {
+					// the disk cache is best-effort, a failure only costs
+					// a round-trip
+					_ = c.store(key, fetchedAt, v)
+				}
+			}
+			delete(c.calls, key)
+			call.value, call.err = v, err
+			c.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+	if call.err != nil {
+		var zero T
+		return zero, call.err
+	}
+	return call.value.(T), nil
+}
+
+// cacheKeyRobots is scoped to the endpoint and credentials of the session,
+// see PasswordSession.cacheScope.
+func cacheKeyRobots(scope string) string { return "robots-" + scope }
+func cacheKeyMaps(serial string) string  { return "maps-" + serial }
+func cacheKeyState(serial string) string { return "state-" + serial }
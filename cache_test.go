@@ -0,0 +1,267 @@
+package neato
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// counter returns a fetch function that counts its calls and returns the
+// call number.
+func counter() (*int32, func(context.Context) (int, error)) {
+	var n int32
+	return &n, func(context.Context) (int, error) {
+		return int(atomic.AddInt32(&n, 1)), nil
+	}
+}
+
+func TestCachedExpiry(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		ttl     time.Duration
+		sleep   time.Duration
+		fetches int32
+	}{
+		{"fresh", time.Hour, 0, 1},
+		{"expired", 20 * time.Millisecond, 40 * time.Millisecond, 2},
+		{"disabled", 0, 0, 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewCache(CacheConfig{})
+			n, fetch := counter()
+			if _, err := cached(context.Background(), c, "key", tc.ttl, fetch, nil); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(tc.sleep)
+			v, err := cached(context.Background(), c, "key", tc.ttl, fetch, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := atomic.LoadInt32(n); got != tc.fetches {
+				t.Errorf("got %d fetches, want %d", got, tc.fetches)
+			}
+			if v != int(tc.fetches) {
+				t.Errorf("got value %d, want %d", v, tc.fetches)
+			}
+		})
+	}
+}
+
+func TestCachedErrorNotCached(t *testing.T) {
+	c := NewCache(CacheConfig{})
+	calls := 0
+	fetch := func(context.Context) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, errors.New("failed")
+		}
+		return calls, nil
+	}
+	if _, err := cached(context.Background(), c, "key", time.Hour, fetch, nil); err == nil {
+		t.Fatal("first call succeeded, want an error")
+	}
+	if v, err := cached(context.Background(), c, "key", time.Hour, fetch, nil); err != nil || v != 2 {
+		t.Errorf("got (%d, %v), want (2, nil)", v, err)
+	}
+}
+
+func TestCachedDedup(t *testing.T) {
+	c := NewCache(CacheConfig{})
+	var n int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fetch := func(context.Context) (int, error) {
+		if atomic.AddInt32(&n, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return 42, nil
+	}
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]int, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := cached(context.Background(), c, "key", time.Hour, fetch, nil)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = v
+		}(i)
+	}
+	<-started
+	close(release)
+	wg.Wait()
+	if got := atomic.LoadInt32(&n); got != 1 {
+		t.Errorf("got %d fetches, want 1", got)
+	}
+	for i, v := range results {
+		if v != 42 {
+			t.Errorf("caller %d got %d, want 42", i, v)
+		}
+	}
+}
+
+func TestCachedCallerContext(t *testing.T) {
+	c := NewCache(CacheConfig{})
+	release := make(chan struct{})
+	fetchErr := make(chan error, 1)
+	fetch := func(ctx context.Context) (int, error) {
+		<-release
+		fetchErr <- ctx.Err()
+		return 42, nil
+	}
+
+	// the caller that starts the fetch gives up, the fetch goes on
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := cached(ctx, c, "key", time.Hour, fetch, nil)
+		done <- err
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("canceled caller is still waiting for the fetch")
+	}
+
+	result := make(chan int, 1)
+	go func() {
+		v, err := cached(context.Background(), c, "key", time.Hour, fetch, nil)
+		if err != nil {
+			t.Error(err)
+		}
+		result <- v
+	}()
+	close(release)
+	if err := <-fetchErr; err != nil {
+		t.Errorf("fetch context is done: %v", err)
+	}
+	if v := <-result; v != 42 {
+		t.Errorf("got %d, want 42", v)
+	}
+}
+
+func TestCachedDisk(t *testing.T) {
+	dir := t.TempDir()
+	n, fetch := counter()
+	var prepared []time.Time
+	prepare := func(_ int, fetchedAt time.Time) {
+		prepared = append(prepared, fetchedAt)
+	}
+	if _, err := cached(context.Background(), NewCache(CacheConfig{Dir: dir}), "key", time.Hour, fetch, prepare); err != nil {
+		t.Fatal(err)
+	}
+	// a new cache, like another process, reads the entry from disk
+	v, err := cached(context.Background(), NewCache(CacheConfig{Dir: dir}), "key", time.Hour, fetch, prepare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(n); got != 1 || v != 1 {
+		t.Errorf("got value %d after %d fetches, want 1 after 1", v, got)
+	}
+	if len(prepared) != 2 || !prepared[0].Equal(prepared[1]) {
+		t.Errorf("got prepare times %v, want twice the same time", prepared)
+	}
+
+	// Clear also removes the entries on disk
+	NewCache(CacheConfig{Dir: dir}).Clear()
+	if _, err := cached(context.Background(), NewCache(CacheConfig{Dir: dir}), "key", time.Hour, fetch, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(n); got != 2 {
+		t.Errorf("got %d fetches after Clear, want 2", got)
+	}
+}
+
+func TestCachedDiskSkipsRobots(t *testing.T) {
+	f := newFakeCloud(t)
+	dir := t.TempDir()
+	if _, err := f.account(NewCache(CacheConfig{RobotsTTL: time.Hour, Dir: dir})).Robots(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(b), f.secretKey) {
+			t.Errorf("cache file %s contains the secret key", e.Name())
+		}
+	}
+}
+
+func TestRobotsContextCanceled(t *testing.T) {
+	f := newFakeCloud(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := f.account(nil).RobotsContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCacheClearDuringFetch(t *testing.T) {
+	c := NewCache(CacheConfig{})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fetch := func(context.Context) (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := cached(context.Background(), c, "key", time.Hour, fetch, nil); err != nil {
+			t.Error(err)
+		}
+	}()
+	<-started
+	c.Clear()
+	close(release)
+	<-done
+
+	n, fetch2 := counter()
+	if _, err := cached(context.Background(), c, "key", time.Hour, fetch2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(n); got != 1 {
+		t.Errorf("a fetch started before Clear populated the cache")
+	}
+}
+
+func TestSessionCacheScope(t *testing.T) {
+	session := func(endpoint, token string) *PasswordSession {
+		header := url.Values{}
+		header.Set("Authorization", "Token token="+token)
+		return NewPasswordSession(endpoint, &header)
+	}
+	a := session("https://beehive.neatocloud.com", "a").cacheScope()
+	if b := session("https://beehive.neatocloud.com", "a").cacheScope(); a != b {
+		t.Errorf("same session has scopes '%s' and '%s'", a, b)
+	}
+	if b := session("https://beehive.neatocloud.com", "b").cacheScope(); a == b {
+		t.Errorf("different tokens share scope '%s'", a)
+	}
+	if b := session("https://example.com", "a").cacheScope(); a == b {
+		t.Errorf("different endpoints share scope '%s'", a)
+	}
+}
@@ -0,0 +1,228 @@
+package neato
+
+import (
+	"context"
+	"fmt"
+)
+
+// Capabilities describes what a robot supports, based on the service versions
+// it advertises in RobotState.AvailableServices. The parameters accepted by
+// Nucleo commands depend on these versions, see
+// https://developers.neatorobotics.com/api/robot-remote-protocol/housecleaning
+//
+// Model and Firmware come from RobotState.Meta, and are used for the limits
+// that do not depend on the service versions, like BoundaryLimits.
+type Capabilities struct {
+	Services AvailableServices
+	Model    string
+	Firmware string
+}
+
+func NewCapabilities(services AvailableServices) *Capabilities {
+	return &Capabilities{Services: services}
+}
+
+// Capabilities fetches the robot state and returns the capabilities derived
+// from it.
+func (r *Robot) Capabilities(ctx context.Context) (*Capabilities, error) {
+	state, err := r.State(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get robot state: %w", err)
+	}
+	return state.Capabilities(), nil
+}
+
+func (s *RobotState) Capabilities() *Capabilities {
+	c := NewCapabilities(s.AvailableServices)
+	c.Model = s.Meta.ModelName
+	c.Firmware = s.Meta.Firmware
+	return c
+}
+
+func (c *Capabilities) SupportsHouseCleaning() bool {
+	return c.Services.HouseCleaning != ""
+}
+
+func (c *Capabilities) SupportsCleaningMode() bool {
+	switch c.Services.HouseCleaning {
+	case "basic-1", "basic-2", "basic-3", "basic-4":
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Capabilities) SupportsNavigationMode() bool {
+	return len(c.NavigationModes()) > 0
+}
+
+// NavigationModes returns the navigation modes accepted by house cleaning, or
+// nil if the navigation mode cannot be chosen. Following the houseCleaning
+// service table of the robot remote protocol, basic-2 and minimal-2 accept
+// normal and extra care, while deep was added with basic-3:
+//
+//	basic-1              no navigationMode parameter
+//	basic-2, minimal-2   1 (normal), 2 (extra care)
+//	basic-3, basic-4     1 (normal), 2 (extra care), 3 (deep)
+func (c *Capabilities) NavigationModes() []NavigationMode {
+	switch c.Services.HouseCleaning {
+	case "basic-2", "minimal-2":
+		return []NavigationMode{NavigationModeNormal, NavigationModeExtraCare}
+	case "basic-3", "basic-4":
+		return []NavigationMode{NavigationModeNormal, NavigationModeExtraCare, NavigationModeDeep}
+	default:
+		return nil
+	}
+}
+
+func (c *Capabilities) SupportsNavigationModeValue(mode NavigationMode) bool {
+	for _, m := range c.NavigationModes() {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Capabilities) SupportsPersistentMaps() bool {
+	switch c.Services.HouseCleaning {
+	case "basic-3", "basic-4":
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsZones reports whether house cleaning can be restricted to a zone
+// of a persistent map via its boundary ID, which was introduced with
+// houseCleaning basic-4.
+func (c *Capabilities) SupportsZones() bool {
+	switch c.Services.HouseCleaning {
+	case "basic-4":
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *Capabilities) SupportsSpotCleaning() bool {
+	return c.Services.SpotCleaning != ""
+}
+
+func (c *Capabilities) SupportsManualCleaning() bool {
+	return c.Services.ManualCleaning != ""
+}
+
+func (c *Capabilities) SupportsSchedule() bool {
+	return c.Services.Schedule != ""
+}
+
+func (c *Capabilities) SupportsFindMe() bool {
+	return c.Services.FindMe != ""
+}
+
+func (c *Capabilities) SupportsMaps() bool {
+	return c.Services.Maps != ""
+}
+
+// SpotCleaningParams lists the parameters accepted by the spotCleaning
+// service.
+type SpotCleaningParams struct {
+	Mode           bool
+	Modifier       bool
+	NavigationMode bool
+	Size           bool
+}
+
+func (c *Capabilities) SpotCleaningParams() SpotCleaningParams {
+	switch c.Services.SpotCleaning {
+	case "basic-1":
+		return SpotCleaningParams{Mode: true, Modifier: true, Size: true}
+	case "basic-2", "basic-3":
+		return SpotCleaningParams{Mode: true, Modifier: true, NavigationMode: true, Size: true}
+	case "minimal-2":
+		return SpotCleaningParams{Modifier: true, NavigationMode: true}
+	case "micro-2":
+		return SpotCleaningParams{NavigationMode: true}
+	default:
+		return SpotCleaningParams{}
+	}
+}
+
+// StartCleaningCommand builds a house cleaning command with the parameters
+// supported by the robot. It fails if opts requests something the robot
+// cannot do.
+func (c *Capabilities) StartCleaningCommand(opts *CleaningOptions) (*StartCleaningCommand, error) {
+	if !c.SupportsHouseCleaning() {
+		return nil, fmt.Errorf("house cleaning is not supported")
+	}
+	cmd := StartCleaningCommand{
+		Category: CategoryNonPersistentMap,
+	}
+	if opts.Category != nil {
+		cmd.Category = *opts.Category
+	} else if c.SupportsPersistentMaps() {
+		cmd.Category = CategoryPersistentMap
+	}
+	if cmd.Category == CategoryPersistentMap && !c.SupportsPersistentMaps() {
+		return nil, fmt.Errorf("persistent maps are not supported by service version '%s'", c.Services.HouseCleaning)
+	}
+	if c.SupportsCleaningMode() {
+		mode := opts.CleaningMode
+		modifier := 1
+		cmd.Mode = &mode
+		cmd.Modifier = &modifier
+	}
+	if c.SupportsNavigationMode() {
+		if !c.SupportsNavigationModeValue(opts.NavigationMode) {
+			return nil, fmt.Errorf("navigation mode '%s' is not supported by service version '%s'", opts.NavigationMode, c.Services.HouseCleaning)
+		}
+		navMode := opts.NavigationMode
+		cmd.NavigationMode = &navMode
+	}
+	if opts.MapID != "" {
+		if !c.SupportsPersistentMaps() {
+			return nil, fmt.Errorf("persistent maps are not supported by service version '%s'", c.Services.HouseCleaning)
+		}
+		cmd.MapID = opts.MapID
+	}
+	if opts.BoundaryID != "" {
+		if !c.SupportsZones() {
+			return nil, fmt.Errorf("zone cleaning is not supported by service version '%s'", c.Services.HouseCleaning)
+		}
+		cmd.BoundaryID = opts.BoundaryID
+	}
+	return &cmd, nil
+}
+
+// SpotCleaningCommand builds a spot cleaning command with the parameters
+// supported by the robot.
+func (c *Capabilities) SpotCleaningCommand(opts *SpotCleaningOptions) (*StartCleaningCommand, error) {
+	if !c.SupportsSpotCleaning() {
+		return nil, fmt.Errorf("spot cleaning is not supported")
+	}
+	params := c.SpotCleaningParams()
+	cmd := StartCleaningCommand{
+		Category: CategorySpot,
+	}
+	if params.Mode {
+		mode := opts.CleaningMode
+		cmd.Mode = &mode
+	}
+	if params.Modifier {
+		modifier := 1
+		if opts.Double {
+			modifier = 2
+		}
+		cmd.Modifier = &modifier
+	}
+	if params.NavigationMode {
+		navMode := opts.NavigationMode
+		cmd.NavigationMode = &navMode
+	}
+	if params.Size {
+		cmd.SpotWidth = opts.Width
+		cmd.SpotHeight = opts.Height
+	}
+	return &cmd, nil
+}
@@ -0,0 +1,132 @@
+package neato
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCapabilitiesZones(t *testing.T) {
+	for _, tc := range []struct {
+		houseCleaning  string
+		persistentMaps bool
+		zones          bool
+	}{
+		{"basic-1", false, false},
+		{"minimal-2", false, false},
+		{"basic-3", true, false},
+		{"basic-4", true, true},
+	} {
+		caps := NewCapabilities(AvailableServices{HouseCleaning: tc.houseCleaning})
+		if got := caps.SupportsPersistentMaps(); got != tc.persistentMaps {
+			t.Errorf("%s: SupportsPersistentMaps() = %v, want %v", tc.houseCleaning, got, tc.persistentMaps)
+		}
+		if got := caps.SupportsZones(); got != tc.zones {
+			t.Errorf("%s: SupportsZones() = %v, want %v", tc.houseCleaning, got, tc.zones)
+		}
+	}
+}
+
+func TestStartCleaningCommandZone(t *testing.T) {
+	opts := NewCleaningOptions()
+	opts.Category = &CategoryPersistentMap
+	opts.MapID = "map"
+	opts.BoundaryID = "zone"
+
+	if _, err := NewCapabilities(AvailableServices{HouseCleaning: "basic-3"}).StartCleaningCommand(opts); err == nil {
+		t.Error("basic-3: expected zone cleaning to be rejected")
+	}
+	cmd, err := NewCapabilities(AvailableServices{HouseCleaning: "basic-4"}).StartCleaningCommand(opts)
+	if err != nil {
+		t.Fatalf("basic-4: %v", err)
+	}
+	if cmd.MapID != "map" || cmd.BoundaryID != "zone" {
+		t.Errorf("basic-4: got map ID '%s' and boundary ID '%s'", cmd.MapID, cmd.BoundaryID)
+	}
+}
+
+func TestCapabilitiesHouseCleaning(t *testing.T) {
+	all := []NavigationMode{NavigationModeNormal, NavigationModeExtraCare, NavigationModeDeep}
+	for _, tc := range []struct {
+		houseCleaning   string
+		supported       bool
+		cleaningMode    bool
+		navigationModes []NavigationMode
+		persistentMaps  bool
+		zones           bool
+	}{
+		{"", false, false, nil, false, false},
+		{"basic-1", true, true, nil, false, false},
+		{"minimal-2", true, false, all[:2], false, false},
+		{"basic-2", true, true, all[:2], false, false},
+		{"basic-3", true, true, all, true, false},
+		{"basic-4", true, true, all, true, true},
+	} {
+		caps := NewCapabilities(AvailableServices{HouseCleaning: tc.houseCleaning})
+		if got := caps.SupportsHouseCleaning(); got != tc.supported {
+			t.Errorf("%q: SupportsHouseCleaning() = %v, want %v", tc.houseCleaning, got, tc.supported)
+		}
+		if got := caps.SupportsCleaningMode(); got != tc.cleaningMode {
+			t.Errorf("%q: SupportsCleaningMode() = %v, want %v", tc.houseCleaning, got, tc.cleaningMode)
+		}
+		if got := caps.NavigationModes(); !reflect.DeepEqual(got, tc.navigationModes) {
+			t.Errorf("%q: NavigationModes() = %v, want %v", tc.houseCleaning, got, tc.navigationModes)
+		}
+		if got, want := caps.SupportsNavigationMode(), len(tc.navigationModes) > 0; got != want {
+			t.Errorf("%q: SupportsNavigationMode() = %v, want %v", tc.houseCleaning, got, want)
+		}
+		if got := caps.SupportsPersistentMaps(); got != tc.persistentMaps {
+			t.Errorf("%q: SupportsPersistentMaps() = %v, want %v", tc.houseCleaning, got, tc.persistentMaps)
+		}
+		if got := caps.SupportsZones(); got != tc.zones {
+			t.Errorf("%q: SupportsZones() = %v, want %v", tc.houseCleaning, got, tc.zones)
+		}
+	}
+}
+
+func TestCapabilitiesSpotCleaning(t *testing.T) {
+	for _, tc := range []struct {
+		spotCleaning string
+		supported    bool
+		params       SpotCleaningParams
+	}{
+		{"", false, SpotCleaningParams{}},
+		{"basic-1", true, SpotCleaningParams{Mode: true, Modifier: true, Size: true}},
+		{"basic-2", true, SpotCleaningParams{Mode: true, Modifier: true, NavigationMode: true, Size: true}},
+		{"basic-3", true, SpotCleaningParams{Mode: true, Modifier: true, NavigationMode: true, Size: true}},
+		{"minimal-2", true, SpotCleaningParams{Modifier: true, NavigationMode: true}},
+		{"micro-2", true, SpotCleaningParams{NavigationMode: true}},
+	} {
+		caps := NewCapabilities(AvailableServices{SpotCleaning: tc.spotCleaning})
+		if got := caps.SupportsSpotCleaning(); got != tc.supported {
+			t.Errorf("%q: SupportsSpotCleaning() = %v, want %v", tc.spotCleaning, got, tc.supported)
+		}
+		if got := caps.SpotCleaningParams(); got != tc.params {
+			t.Errorf("%q: SpotCleaningParams() = %+v, want %+v", tc.spotCleaning, got, tc.params)
+		}
+	}
+}
+
+func TestStartCleaningCommandNavigationMode(t *testing.T) {
+	for _, tc := range []struct {
+		houseCleaning string
+		mode          NavigationMode
+		wantErr       bool
+	}{
+		{"basic-2", NavigationModeExtraCare, false},
+		{"basic-2", NavigationModeDeep, true},
+		{"minimal-2", NavigationModeDeep, true},
+		{"basic-3", NavigationModeDeep, false},
+		{"basic-4", NavigationModeDeep, false},
+	} {
+		opts := NewCleaningOptions()
+		opts.NavigationMode = tc.mode
+		cmd, err := NewCapabilities(AvailableServices{HouseCleaning: tc.houseCleaning}).StartCleaningCommand(opts)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s, %s: got error %v, want error: %v", tc.houseCleaning, tc.mode, err, tc.wantErr)
+			continue
+		}
+		if err == nil && (cmd.NavigationMode == nil || *cmd.NavigationMode != tc.mode) {
+			t.Errorf("%s, %s: got navigation mode %v", tc.houseCleaning, tc.mode, cmd.NavigationMode)
+		}
+	}
+}
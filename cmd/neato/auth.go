@@ -22,5 +22,5 @@ func getAccount() (*neato.Account, error) {
 		return nil, fmt.Errorf("no session.endpoint or session.header.Authorization found in configuration file, you need to log in first")
 	}
 	s := neato.NewPasswordSession(endpoint, &header)
-	return neato.NewAccount(s), nil
+	return neato.NewAccountWithCache(s, neato.NewCache(cacheConfig())), nil
 }
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/insomniacslk/neato"
+	"github.com/insomniacslk/neato/maps"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagBoundariesExportFormat  string
+	flagBoundariesExportOutput  string
+	flagBoundariesImportMapID   string
+	flagBoundariesValidateRobot string
+)
+
+var boundariesCmd = &cobra.Command{
+	Use:   "boundaries",
+	Short: "Manage the zones and no-go lines of persistent maps",
+}
+
+var boundariesExportCmd = &cobra.Command{
+	Use:   "export <persistent-map-id>",
+	Short: "Export the boundaries of a persistent map as SVG, GeoJSON or YAML",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		robot, pm := findPersistentMap(args[0])
+		var buf bytes.Buffer
+		if err := pm.Download(context.Background(), &buf); err != nil {
+			log.Fatalf("Failed to download persistent map: %v", err)
+		}
+		img, _, err := image.Decode(&buf)
+		if err != nil {
+			log.Fatalf("Failed to decode persistent map image: %v", err)
+		}
+		boundaries, err := robot.MapBoundaries(context.Background(), pm.ID)
+		if err != nil {
+			log.Fatalf("Failed to get boundaries: %v", err)
+		}
+		v := maps.NewVectorMap(pm.ID, img, nil, boundaries)
+		if err := writeVectorMap(v, flagBoundariesExportFormat, flagBoundariesExportOutput); err != nil {
+			log.Fatalf("Failed to export boundaries: %v", err)
+		}
+	},
+}
+
+var boundariesImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Upload the boundaries from an SVG, GeoJSON or YAML file to the robot",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		mapID, boundaries, err := readBoundariesFile(args[0])
+		if err != nil {
+			log.Fatalf("Failed to read boundaries: %v", err)
+		}
+		if flagBoundariesImportMapID != "" {
+			mapID = flagBoundariesImportMapID
+		}
+		if mapID == "" {
+			log.Fatalf("No persistent map ID found in '%s', use --map-id", args[0])
+		}
+		robot, pm := findPersistentMap(mapID)
+		caps, err := robot.Capabilities(context.Background())
+		if err != nil {
+			log.Fatalf("Failed to get capabilities of robot '%s': %v", robot.Name, err)
+		}
+		// errors are reported by SetMapBoundaries, which refuses to upload
+		for _, p := range neato.ValidateBoundaries(boundaries, caps.BoundaryLimits()) {
+			if p.Severity != neato.SeverityError {
+				fmt.Fprintln(os.Stderr, p)
+			}
+		}
+		if err := robot.SetMapBoundaries(context.Background(), pm.ID, boundaries); err != nil {
+			log.Fatalf("Failed to upload boundaries: %v", err)
+		}
+		log.Printf("Uploaded %d boundaries to persistent map '%s' of robot '%s'", len(boundaries), pm.ID, robot.Name)
+	},
+}
+
+var boundariesValidateCmd = &cobra.Command{
+	Use:   "validate <file>",
+	Short: "Check the boundaries in an SVG, GeoJSON or YAML file without uploading them",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		_, boundaries, err := readBoundariesFile(args[0])
+		if err != nil {
+			log.Fatalf("Failed to read boundaries: %v", err)
+		}
+		limits := neato.DefaultBoundaryLimits
+		if flagBoundariesValidateRobot != "" {
+			robot := findRobot(flagBoundariesValidateRobot)
+			caps, err := robot.Capabilities(context.Background())
+			if err != nil {
+				log.Fatalf("Failed to get capabilities of robot '%s': %v", robot.Name, err)
+			}
+			limits = caps.BoundaryLimits()
+		}
+		problems := neato.ValidateBoundaries(boundaries, limits)
+		if flagJSON {
+			j, err := json.Marshal(problems)
+			if err != nil {
+				log.Fatalf("Failed to marshal to JSON: %v", err)
+			}
+			fmt.Println(string(j))
+		} else {
+			for _, p := range problems {
+				fmt.Println(p)
+			}
+			if len(problems) == 0 {
+				fmt.Printf("%d boundaries, no problems found\n", len(boundaries))
+			}
+		}
+		if problems.HasErrors() {
+			os.Exit(1)
+		}
+	},
+}
+
+// findRobot looks up a robot by serial number.
+func findRobot(serial string) *neato.Robot {
+	acc, err := getAccount()
+	if err != nil {
+		log.Fatalf("Account lookup failed: %v", err)
+	}
+	robot, err := acc.RobotBySerial(serial)
+	if err != nil {
+		log.Fatalf("Cannot get robot: %v", err)
+	}
+	return robot
+}
+
+// readBoundariesFile reads a boundaries file, choosing the format from its
+// extension.
+func readBoundariesFile(name string) (string, []*neato.Boundary, error) {
+	fd, err := os.Open(name)
+	if err != nil {
+		return "", nil, err
+	}
+	defer fd.Close()
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".svg":
+		return maps.ReadBoundariesSVG(fd)
+	case ".geojson", ".json":
+		return maps.ReadBoundariesGeoJSON(fd)
+	case ".yaml", ".yml":
+		return maps.ReadBoundariesYAML(fd)
+	default:
+		return "", nil, fmt.Errorf("unsupported file extension '%s', must be one of .svg, .geojson, .json, .yaml or .yml", filepath.Ext(name))
+	}
+}
+
+// findPersistentMap looks up the robot that owns the given persistent map.
+func findPersistentMap(id string) (*neato.Robot, *neato.PersistentMap) {
+	acc, err := getAccount()
+	if err != nil {
+		log.Fatalf("Account lookup failed: %v", err)
+	}
+	robots, err := acc.Robots()
+	if err != nil {
+		log.Fatalf("Cannot get robots: %v", err)
+	}
+	for _, r := range robots {
+		pms, err := r.PersistentMaps()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get persistent maps for robot '%s' (serial: '%s'): %v\n", r.Name, r.Serial, err)
+			continue
+		}
+		for _, pm := range pms {
+			if pm.ID == id {
+				return r, pm
+			}
+		}
+	}
+	log.Fatalf("Persistent map '%s' not found", id)
+	return nil, nil
+}
+
+func initBoundariesCmd() {
+	boundariesCmd.AddCommand(boundariesExportCmd)
+	boundariesCmd.AddCommand(boundariesImportCmd)
+	boundariesCmd.AddCommand(boundariesValidateCmd)
+	boundariesExportCmd.Flags().StringVarP(&flagBoundariesExportFormat, "format", "f", "svg", "Output format, one of 'svg', 'geojson' or 'yaml'")
+	boundariesExportCmd.Flags().StringVarP(&flagBoundariesExportOutput, "output", "o", "", "File to save the export to, by default stdout")
+	boundariesImportCmd.Flags().StringVarP(&flagBoundariesImportMapID, "map-id", "m", "", "Persistent map ID, overrides the one in the file")
+	boundariesValidateCmd.Flags().StringVarP(&flagBoundariesValidateRobot, "robot", "r", "", "Serial number of the robot whose limits to check against, instead of the default ones")
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"path"
+	"time"
+
+	"github.com/insomniacslk/neato"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the on-disk cache of API responses",
+	Args:  cobra.MinimumNArgs(1),
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached API response",
+	Run: func(cmd *cobra.Command, args []string) {
+		config := cacheConfig()
+		config.Dir = responseCacheDir()
+		neato.NewCache(config).Clear()
+		log.Printf("Cleared cache directory '%s'", config.Dir)
+	},
+}
+
+func initCacheCmd() {
+	cacheCmd.AddCommand(cacheClearCmd)
+}
+
+func responseCacheDir() string {
+	return path.Join(path.Dir(flagConfigFile), "cache")
+}
+
+// cacheConfig returns the cache configuration, reading the TTLs from the
+// cache.robots_ttl, cache.maps_ttl and cache.state_ttl config directives.
+// The on-disk cache is only enabled with --cache.
+func cacheConfig() neato.CacheConfig {
+	config := neato.DefaultCacheConfig
+	ttls := map[string]*time.Duration{
+		"cache.robots_ttl": &config.RobotsTTL,
+		"cache.maps_ttl":   &config.MapsTTL,
+		"cache.state_ttl":  &config.StateTTL,
+	}
+	for directive, ttl := range ttls {
+		if viper.IsSet(directive) {
+			*ttl = viper.GetDuration(directive)
+		}
+	}
+	if flagCache {
+		config.Dir = responseCacheDir()
+	}
+	return config
+}
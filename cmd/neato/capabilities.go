@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/insomniacslk/neato"
+	"github.com/spf13/cobra"
+)
+
+var capabilitiesCmd = &cobra.Command{
+	Use:   "capabilities",
+	Short: "Show the services and features supported by every robot",
+	Run: func(cmd *cobra.Command, args []string) {
+		acc, err := getAccount()
+		if err != nil {
+			log.Fatalf("Account lookup failed: %v", err)
+		}
+		robots, err := acc.Robots()
+		if err != nil {
+			log.Fatalf("Cannot get robots: %v", err)
+		}
+		if len(robots) == 0 {
+			fmt.Println("No robots found")
+			return
+		}
+		states, err := acc.States(context.Background(), flagConcurrency)
+		if err != nil {
+			log.Fatalf("Cannot get robot states: %v", err)
+		}
+		for _, r := range robots {
+			res := states[r.Serial]
+			if res.Err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to get capabilities for robot '%s' (serial: '%s'): %v\n", r.Name, r.Serial, res.Err)
+				continue
+			}
+			caps := res.State.Capabilities()
+			if flagJSON {
+				j, err := json.Marshal(robotCapabilities{
+					Name:     r.Name,
+					Serial:   r.Serial,
+					Model:    caps.Model,
+					Firmware: caps.Firmware,
+					Services: capabilityMatrix(caps),
+				})
+				if err != nil {
+					log.Fatalf("Failed to marshal to JSON: %v", err)
+				}
+				fmt.Println(string(j))
+			} else {
+				fmt.Printf("Robot '%s' (serial: '%s')\n", r.Name, r.Serial)
+				printCapabilities(caps)
+			}
+		}
+	},
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// robotCapabilities is the JSON output of the capabilities command for one
+// robot.
+type robotCapabilities struct {
+	Name     string              `json:"name"`
+	Serial   string              `json:"serial"`
+	Model    string              `json:"model"`
+	Firmware string              `json:"firmware"`
+	Services []serviceCapability `json:"services"`
+}
+
+// serviceCapability is a row of the capability matrix: a service, the version
+// advertised by the robot, and the features it provides at that version.
+type serviceCapability struct {
+	Service   string          `json:"service"`
+	Version   string          `json:"version"`
+	Supported bool            `json:"supported"`
+	Features  map[string]bool `json:"features,omitempty"`
+	// NavigationModes lists the accepted navigation modes, if they can be
+	// chosen.
+	NavigationModes []neato.NavigationMode `json:"navigationModes,omitempty"`
+}
+
+func capabilityMatrix(caps *neato.Capabilities) []serviceCapability {
+	s := caps.Services
+	spot := caps.SpotCleaningParams()
+	return []serviceCapability{
+		{
+			Service:   "houseCleaning",
+			Version:   s.HouseCleaning,
+			Supported: caps.SupportsHouseCleaning(),
+			Features: map[string]bool{
+				"cleaningMode":   caps.SupportsCleaningMode(),
+				"navigationMode": caps.SupportsNavigationMode(),
+				"persistentMaps": caps.SupportsPersistentMaps(),
+				"zones":          caps.SupportsZones(),
+			},
+			NavigationModes: caps.NavigationModes(),
+		},
+		{
+			Service:   "spotCleaning",
+			Version:   s.SpotCleaning,
+			Supported: caps.SupportsSpotCleaning(),
+			Features: map[string]bool{
+				"cleaningMode":   spot.Mode,
+				"modifier":       spot.Modifier,
+				"navigationMode": spot.NavigationMode,
+				"spotSize":       spot.Size,
+			},
+		},
+		{Service: "manualCleaning", Version: s.ManualCleaning, Supported: caps.SupportsManualCleaning()},
+		{Service: "maps", Version: s.Maps, Supported: caps.SupportsMaps()},
+		{Service: "schedule", Version: s.Schedule, Supported: caps.SupportsSchedule()},
+		{Service: "findMe", Version: s.FindMe, Supported: caps.SupportsFindMe()},
+	}
+}
+
+// featureLabels are the readable names of the features in the capability
+// matrix, in the order they are printed.
+var featureLabels = []struct{ key, label string }{
+	{"cleaningMode", "cleaning mode"},
+	{"modifier", "modifier"},
+	{"navigationMode", "navigation mode"},
+	{"spotSize", "spot size"},
+	{"persistentMaps", "persistent maps"},
+	{"zones", "zones"},
+}
+
+func printCapabilities(caps *neato.Capabilities) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, svc := range capabilityMatrix(caps) {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", svc.Service, svc.Version, yesNo(svc.Supported))
+		for _, f := range featureLabels {
+			supported, ok := svc.Features[f.key]
+			if !ok {
+				continue
+			}
+			if f.key == "navigationMode" && svc.NavigationModes != nil {
+				fmt.Fprintf(w, "    %s\t\t%s %v\n", f.label, yesNo(supported), svc.NavigationModes)
+			} else {
+				fmt.Fprintf(w, "    %s\t\t%s\n", f.label, yesNo(supported))
+			}
+		}
+	}
+	w.Flush()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/insomniacslk/neato"
+)
+
+func TestCapabilityMatrixJSON(t *testing.T) {
+	caps := neato.NewCapabilities(neato.AvailableServices{
+		HouseCleaning: "basic-2",
+		SpotCleaning:  "micro-2",
+		Maps:          "basic-1",
+	})
+	j, err := json.Marshal(capabilityMatrix(caps))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []struct {
+		Service         string          `json:"service"`
+		Version         string          `json:"version"`
+		Supported       bool            `json:"supported"`
+		Features        map[string]bool `json:"features"`
+		NavigationModes []string        `json:"navigationModes"`
+	}
+	if err := json.Unmarshal(j, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 6 {
+		t.Fatalf("got %d services, want 6: %s", len(got), j)
+	}
+	house, spot, manual, maps := got[0], got[1], got[2], got[3]
+	if house.Service != "houseCleaning" || house.Version != "basic-2" || !house.Supported {
+		t.Errorf("got house cleaning %+v", house)
+	}
+	if !house.Features["cleaningMode"] || !house.Features["navigationMode"] || house.Features["persistentMaps"] || house.Features["zones"] {
+		t.Errorf("got house cleaning features %v", house.Features)
+	}
+	if len(house.NavigationModes) != 2 || house.NavigationModes[0] != "normal" || house.NavigationModes[1] != "extra_care" {
+		t.Errorf("got navigation modes %v, want [normal extra_care]", house.NavigationModes)
+	}
+	if spot.Service != "spotCleaning" || !spot.Features["navigationMode"] || spot.Features["spotSize"] {
+		t.Errorf("got spot cleaning %+v", spot)
+	}
+	if manual.Service != "manualCleaning" || manual.Supported || manual.Features != nil {
+		t.Errorf("got manual cleaning %+v", manual)
+	}
+	if maps.Service != "maps" || maps.Version != "basic-1" || !maps.Supported {
+		t.Errorf("got maps %+v", maps)
+	}
+}
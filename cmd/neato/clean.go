@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/neato"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagCleanMode            string
+	flagCleanNavigation      string
+	flagCleanMinCharge       int
+	flagCleanIdleTimeout     time.Duration
+	flagCleanRechargeTimeout time.Duration
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean <room[:mode]>...",
+	Short: "Clean one or more rooms by name, one after the other",
+	Long: `Clean one or more rooms by name. With more than one room, the rooms are
+cleaned in order and the command waits for each of them to be done,
+recharging the robot in between if needed. The cleaning mode can be set
+per room with a suffix, e.g. "kitchen:turbo".`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var navMode neato.NavigationMode
+		if err := navMode.UnmarshalText([]byte(flagCleanNavigation)); err != nil {
+			log.Fatalf("Invalid --navigation: %v", err)
+		}
+		acc, err := getAccount()
+		if err != nil {
+			log.Fatalf("Account lookup failed: %v", err)
+		}
+		q := neato.NewJobQueue()
+		q.MinCharge = flagCleanMinCharge
+		q.IdleTimeout = flagCleanIdleTimeout
+		q.RechargeTimeout = flagCleanRechargeTimeout
+		q.OnEvent = func(ev neato.JobEvent) {
+			switch ev.Kind {
+			case neato.JobEventProgress:
+				log.Printf("[%d/%d] Room '%s' on robot '%s': %s", ev.Index+1, len(q.Jobs), ev.Job.Name, ev.Job.Robot.Name, ev.Progress.Phase)
+			case neato.JobEventFinished:
+				log.Printf("[%d/%d] Room '%s' on robot '%s': finished, %s", ev.Index+1, len(q.Jobs), ev.Job.Name, ev.Job.Robot.Name, ev.Summary)
+			default:
+				log.Printf("[%d/%d] Room '%s' on robot '%s': %s", ev.Index+1, len(q.Jobs), ev.Job.Name, ev.Job.Robot.Name, ev.Kind)
+			}
+		}
+		for _, arg := range args {
+			name, modeStr := arg, flagCleanMode
+			if idx := strings.LastIndex(arg, ":"); idx >= 0 {
+				name, modeStr = arg[:idx], arg[idx+1:]
+			}
+			var mode neato.CleaningMode
+			if err := mode.UnmarshalText([]byte(modeStr)); err != nil {
+				log.Fatalf("Invalid cleaning mode for room '%s': %v", name, err)
+			}
+			room, err := lookupRoom(acc, name)
+			if err != nil {
+				log.Fatalf("Cannot find room: %v", err)
+			}
+			robot, err := acc.RobotBySerial(room.RobotSerial)
+			if err != nil {
+				log.Fatalf("Cannot get robot for room '%s': %v", room.Name, err)
+			}
+			q.AddRoom(robot, room, mode, navMode)
+		}
+		if len(q.Jobs) == 1 {
+			job := q.Jobs[0]
+			if err := job.Robot.Start(context.Background(), job.Options); err != nil {
+				log.Fatalf("Failed to start cleaning room '%s': %v", job.Name, err)
+			}
+			log.Printf("Robot '%s' started cleaning room '%s'", job.Robot.Name, job.Name)
+			return
+		}
+		if err := q.Run(context.Background()); err != nil {
+			log.Fatalf("Cleaning failed: %v", err)
+		}
+	},
+}
+
+func initCleanCmd() {
+	cleanCmd.Flags().StringVarP(&flagCleanMode, "mode", "m", "eco", "Default cleaning mode, one of 'eco' or 'turbo'")
+	cleanCmd.Flags().StringVarP(&flagCleanNavigation, "navigation", "n", "normal", "Navigation mode, one of 'normal', 'extra_care' or 'deep'")
+	cleanCmd.Flags().IntVarP(&flagCleanMinCharge, "min-charge", "", 30, "Battery percentage below which the robot recharges before the next room")
+	cleanCmd.Flags().DurationVarP(&flagCleanIdleTimeout, "idle-timeout", "", 2*time.Hour, "How long to wait for the robot to be idle before the next room, 0 for no limit")
+	cleanCmd.Flags().DurationVarP(&flagCleanRechargeTimeout, "recharge-timeout", "", 4*time.Hour, "How long to wait for the robot to recharge before the next room, 0 for no limit")
+}
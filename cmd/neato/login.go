@@ -30,6 +30,9 @@ var loginCmd = &cobra.Command{
 			log.Fatalf("Failed to save to config file: %v", err)
 		}
 		log.Printf("Saved session to config file '%s'", viper.ConfigFileUsed())
+		// responses cached for the previous session must not be served to
+		// the new one
+		neato.NewCache(neato.CacheConfig{Dir: responseCacheDir()}).Clear()
 	},
 }
 
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path"
 
+	"github.com/insomniacslk/neato"
 	"github.com/kirsle/configdir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -16,10 +17,13 @@ const progname = "neato"
 var (
 	defaultConfigFile = path.Join(configdir.LocalConfig(progname), "config.yml")
 
-	flagConfigFile string
-	flagToken      string
-	flagDebug      bool
-	flagJSON       bool
+	flagConfigFile  string
+	flagToken       string
+	flagDebug       bool
+	flagJSON        bool
+	flagJSONEnums   string
+	flagConcurrency int
+	flagCache       bool
 )
 
 var rootCmd = &cobra.Command{
@@ -36,6 +40,9 @@ func init() {
 	rootCmd.PersistentFlags().StringVarP(&flagToken, "token", "t", "", "Authentication token")
 	rootCmd.PersistentFlags().BoolVarP(&flagDebug, "debug", "D", false, "Show debug output")
 	rootCmd.PersistentFlags().BoolVarP(&flagJSON, "json", "j", false, "Print output as JSON")
+	rootCmd.PersistentFlags().IntVarP(&flagConcurrency, "concurrency", "", neato.DefaultConcurrency, "Maximum number of robots to query in parallel")
+	rootCmd.PersistentFlags().BoolVarP(&flagCache, "cache", "", false, "Cache API responses on disk across invocations")
+	rootCmd.PersistentFlags().StringVarP(&flagJSONEnums, "json-enums", "", "names", "How to print enums in JSON output, one of 'names' or 'numbers'")
 
 	// flag-name to config-directive mapping
 	flagMapping := map[string]string{
@@ -53,15 +60,36 @@ func init() {
 	rootCmd.AddCommand(stateCmd)
 	rootCmd.AddCommand(startCmd)
 	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(capabilitiesCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(boundariesCmd)
+	rootCmd.AddCommand(roomsCmd)
+	rootCmd.AddCommand(cleanCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(serveCmd)
 	initLoginCmd()
 	initRobotsCmd()
 	initMapsCmd()
 	initStateCmd()
 	initStartCmd()
 	initStopCmd()
+	initStatsCmd()
+	initBoundariesCmd()
+	initRoomsCmd()
+	initCleanCmd()
+	initWatchCmd()
+	initCacheCmd()
+	initServeCmd()
 }
 
 func initConfig() {
+	format, err := neato.ParseEnumFormat(flagJSONEnums)
+	if err != nil {
+		log.Fatalf("Invalid --json-enums: %v", err)
+	}
+	neato.EnumJSONFormat = format
+
 	viper.SetConfigFile(flagConfigFile)
 	viper.AutomaticEnv()
 	if err := viper.ReadInConfig(); err == nil {
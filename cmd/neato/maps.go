@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -29,8 +30,12 @@ var mapsCmd = &cobra.Command{
 			fmt.Println("No robots found")
 			return
 		}
+		results, err := acc.MapsByRobot(context.Background(), flagConcurrency)
+		if err != nil {
+			log.Fatalf("Cannot get maps: %v", err)
+		}
 		for _, r := range robots {
-			maps, err := r.Maps()
+			maps, err := results[r.Serial].Maps, results[r.Serial].Err
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to get map for robot '%s' (serial: '%s'): %v\n", r.Name, r.Serial, err)
 				continue
@@ -55,5 +60,15 @@ var mapsCmd = &cobra.Command{
 }
 
 func initMapsCmd() {
+	mapsCmd.AddCommand(mapsDownloadCmd)
+	initMapsDownloadCmd()
+	mapsCmd.AddCommand(mapsShowCmd)
+	initMapsShowCmd()
+	mapsCmd.AddCommand(mapsHeatmapCmd)
+	initMapsHeatmapCmd()
+	mapsCmd.AddCommand(mapsDiffCmd)
+	initMapsDiffCmd()
+	mapsCmd.AddCommand(mapsExportCmd)
+	initMapsExportCmd()
 	mapsCmd.Flags().BoolVarP(&flagMapsShowAll, "--show-all", "a", false, "Show all the maps for each robot instead of the most recent one")
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/insomniacslk/neato"
+	"github.com/insomniacslk/neato/maps"
+	"github.com/spf13/cobra"
+)
+
+var flagMapsDiffOutput string
+
+var mapsDiffCmd = &cobra.Command{
+	Use:   "diff <map-id-1> <map-id-2>",
+	Short: "Highlight the areas cleaned in one run but not in the other",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		acc, err := getAccount()
+		if err != nil {
+			log.Fatalf("Account lookup failed: %v", err)
+		}
+		allMaps, err := acc.Maps()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to get some maps: %v\n", err)
+		}
+		ma, mb := findMap(allMaps, args[0]), findMap(allMaps, args[1])
+		if ma == nil {
+			log.Fatalf("Map '%s' not found", args[0])
+		}
+		if mb == nil {
+			log.Fatalf("Map '%s' not found", args[1])
+		}
+		cache := neato.NewMapCache(mapCacheDir())
+		imgA, err := loadMapImage(cache, ma)
+		if err != nil {
+			log.Fatalf("Failed to load map '%s': %v", ma.ID, err)
+		}
+		imgB, err := loadMapImage(cache, mb)
+		if err != nil {
+			log.Fatalf("Failed to load map '%s': %v", mb.ID, err)
+		}
+		d := maps.NewDiff(imgA, imgB, nil, maps.DefaultMaxOffset)
+
+		output := flagMapsDiffOutput
+		if output == "" {
+			output = fmt.Sprintf("diff-%s-%s.png", ma.ID, mb.ID)
+		}
+		fd, err := os.Create(output)
+		if err != nil {
+			log.Fatalf("Failed to create '%s': %v", output, err)
+		}
+		if err := d.WritePNG(fd); err != nil {
+			log.Fatalf("Failed to write '%s': %v", output, err)
+		}
+		if err := fd.Close(); err != nil {
+			log.Fatalf("Failed to write '%s': %v", output, err)
+		}
+
+		fmt.Printf("1) %s\n", ma)
+		fmt.Printf("2) %s\n", mb)
+		if ma.CleanedArea != nil && mb.CleanedArea != nil {
+			fmt.Printf("Cleaned area: %.2f sqm -> %.2f sqm (%+.2f sqm)\n", *ma.CleanedArea, *mb.CleanedArea, *mb.CleanedArea-*ma.CleanedArea)
+		}
+		// estimate the size of a pixel from the first run
+		sqmPerPixel := 0.0
+		if ma.CleanedArea != nil && d.AreaA() > 0 {
+			sqmPerPixel = *ma.CleanedArea / float64(d.AreaA())
+		}
+		printDiffArea("Cleaned in both runs", d.Both, sqmPerPixel)
+		printDiffArea("Cleaned only in run 1 (red)", d.OnlyA, sqmPerPixel)
+		printDiffArea("Cleaned only in run 2 (green)", d.OnlyB, sqmPerPixel)
+		fmt.Printf("Alignment offset: %s\n", d.Offset)
+		log.Printf("Saved diff to '%s'", output)
+	},
+}
+
+func printDiffArea(label string, pixels int, sqmPerPixel float64) {
+	if sqmPerPixel > 0 {
+		fmt.Printf("%s: ~%.2f sqm (%d pixels)\n", label, float64(pixels)*sqmPerPixel, pixels)
+	} else {
+		fmt.Printf("%s: %d pixels\n", label, pixels)
+	}
+}
+
+func findMap(ms []*neato.Map, id string) *neato.Map {
+	for _, m := range ms {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+func initMapsDiffCmd() {
+	mapsDiffCmd.Flags().StringVarP(&flagMapsDiffOutput, "output", "o", "", "File to save the diff image to, by default diff-<id1>-<id2>.png")
+}
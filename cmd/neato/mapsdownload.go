@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/insomniacslk/neato"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagMapsDownloadAll    bool
+	flagMapsDownloadOutDir string
+)
+
+var mapsDownloadCmd = &cobra.Command{
+	Use:   "download",
+	Short: "Download the most recent map image of every robot",
+	Run: func(cmd *cobra.Command, args []string) {
+		acc, err := getAccount()
+		if err != nil {
+			log.Fatalf("Account lookup failed: %v", err)
+		}
+		robots, err := acc.Robots()
+		if err != nil {
+			log.Fatalf("Cannot get robots: %v", err)
+		}
+		if len(robots) == 0 {
+			fmt.Println("No robots found")
+			return
+		}
+		if err := os.MkdirAll(flagMapsDownloadOutDir, 0o755); err != nil {
+			log.Fatalf("Failed to create output directory: %v", err)
+		}
+		cache := neato.NewMapCache(mapCacheDir())
+		for _, r := range robots {
+			maps, err := r.Maps()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to get maps for robot '%s' (serial: '%s'): %v\n", r.Name, r.Serial, err)
+				continue
+			}
+			if !flagMapsDownloadAll && len(maps) > 0 {
+				maps = maps[:1]
+			}
+			for _, m := range maps {
+				dst, err := mapImageFile(flagMapsDownloadOutDir, m.ID)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Skipping map of robot '%s': %v\n", r.Name, err)
+					continue
+				}
+				if _, err := os.Stat(dst); err == nil {
+					log.Printf("Skipping map '%s', '%s' already exists", m.ID, dst)
+					continue
+				}
+				src, err := cache.Fetch(context.Background(), m)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to download map '%s' for robot '%s': %v\n", m.ID, r.Name, err)
+					continue
+				}
+				if err := copyFile(src, dst); err != nil {
+					log.Fatalf("Failed to save map '%s': %v", m.ID, err)
+				}
+				log.Printf("Saved map '%s' of robot '%s' to '%s'", m.ID, r.Name, dst)
+			}
+		}
+	},
+}
+
+// mapImageFile returns the path in dir where the image of the given map is
+// saved. Map IDs come from the server, so IDs that are not a plain file name
+// are rejected rather than allowed to point outside of dir.
+func mapImageFile(dir, mapID string) (string, error) {
+	if mapID == "" || mapID == "." || mapID == ".." || strings.ContainsAny(mapID, `/\`) || filepath.Base(mapID) != mapID {
+		return "", fmt.Errorf("invalid map ID '%s'", mapID)
+	}
+	return filepath.Join(dir, mapID+".png"), nil
+}
+
+func mapCacheDir() string {
+	return path.Join(path.Dir(flagConfigFile), "maps")
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func initMapsDownloadCmd() {
+	mapsDownloadCmd.Flags().BoolVarP(&flagMapsDownloadAll, "all", "a", false, "Download all the maps for each robot instead of the most recent one")
+	mapsDownloadCmd.Flags().StringVarP(&flagMapsDownloadOutDir, "output", "o", ".", "Directory to save the map images to")
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMapImageFile(t *testing.T) {
+	dir := filepath.Join("out", "maps")
+	for _, tc := range []struct {
+		id      string
+		want    string
+		wantErr bool
+	}{
+		{"2026-10-19T100000Z", filepath.Join(dir, "2026-10-19T100000Z.png"), false},
+		{"map..1", filepath.Join(dir, "map..1.png"), false},
+		{"", "", true},
+		{".", "", true},
+		{"..", "", true},
+		{"../../etc/cron.d/x", "", true},
+		{"/etc/passwd", "", true},
+		{"a/b", "", true},
+		{`..\..\x`, "", true},
+	} {
+		got, err := mapImageFile(dir, tc.id)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: got error %v, want error: %v", tc.id, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.id, got, tc.want)
+		}
+	}
+}
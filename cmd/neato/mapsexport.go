@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"os"
+
+	"github.com/insomniacslk/neato"
+	"github.com/insomniacslk/neato/maps"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagMapsExportFormat string
+	flagMapsExportOutput string
+)
+
+var mapsExportCmd = &cobra.Command{
+	Use:   "export <map-id>",
+	Short: "Export a map and the boundaries of its persistent map as SVG, GeoJSON or YAML",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		acc, err := getAccount()
+		if err != nil {
+			log.Fatalf("Account lookup failed: %v", err)
+		}
+		robots, err := acc.Robots()
+		if err != nil {
+			log.Fatalf("Cannot get robots: %v", err)
+		}
+		var (
+			robot *neato.Robot
+			m     *neato.Map
+		)
+		for _, r := range robots {
+			robotMaps, err := r.Maps()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to get maps for robot '%s' (serial: '%s'): %v\n", r.Name, r.Serial, err)
+				continue
+			}
+			if m = findMap(robotMaps, args[0]); m != nil {
+				robot = r
+				break
+			}
+		}
+		if m == nil {
+			log.Fatalf("Map '%s' not found", args[0])
+		}
+		img, err := loadMapImage(neato.NewMapCache(mapCacheDir()), m)
+		if err != nil {
+			log.Fatalf("Failed to load map '%s': %v", m.ID, err)
+		}
+		v := maps.NewVectorMap(m.ID, img, nil, nil)
+		if m.PersistentMapID != nil && *m.PersistentMapID != "" {
+			// boundaries are normalized to the persistent map image, which
+			// does not have the same size as the image of the run
+			fv, err := floorPlanVectorMap(robot, *m.PersistentMapID, m.ID, img)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Exporting without boundaries: %v\n", err)
+			} else {
+				v = fv
+			}
+		}
+		if err := writeVectorMap(v, flagMapsExportFormat, flagMapsExportOutput); err != nil {
+			log.Fatalf("Failed to export map: %v", err)
+		}
+	},
+}
+
+// floorPlanVectorMap vectorizes the run image img in the frame of the given
+// persistent map, together with its boundaries.
+func floorPlanVectorMap(robot *neato.Robot, persistentMapID, mapID string, img image.Image) (*maps.VectorMap, error) {
+	pms, err := robot.PersistentMaps()
+	if err != nil {
+		return nil, err
+	}
+	var pm *neato.PersistentMap
+	for _, p := range pms {
+		if p.ID == persistentMapID {
+			pm = p
+			break
+		}
+	}
+	if pm == nil {
+		return nil, fmt.Errorf("persistent map '%s' not found", persistentMapID)
+	}
+	var buf bytes.Buffer
+	if err := pm.Download(context.Background(), &buf); err != nil {
+		return nil, err
+	}
+	floorPlan, _, err := image.Decode(&buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode persistent map image: %w", err)
+	}
+	boundaries, err := robot.MapBoundaries(context.Background(), pm.ID)
+	if err != nil {
+		return nil, err
+	}
+	return maps.NewFloorPlanVectorMap(mapID, img, floorPlan, nil, maps.DefaultMaxOffset, boundaries), nil
+}
+
+// writeVectorMap writes v in the given format to the output file, or to
+// stdout if output is empty.
+func writeVectorMap(v *maps.VectorMap, format, output string) error {
+	var write func(io.Writer) error
+	switch format {
+	case "svg":
+		write = v.WriteSVG
+	case "geojson":
+		write = v.WriteGeoJSON
+	case "yaml":
+		write = v.WriteYAML
+	default:
+		return fmt.Errorf("unsupported format '%s', must be one of 'svg', 'geojson' or 'yaml'", format)
+	}
+	if output == "" {
+		return write(os.Stdout)
+	}
+	fd, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	if err := write(fd); err != nil {
+		fd.Close()
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		return err
+	}
+	log.Printf("Saved to '%s'", output)
+	return nil
+}
+
+func initMapsExportCmd() {
+	mapsExportCmd.Flags().StringVarP(&flagMapsExportFormat, "format", "f", "svg", "Output format, one of 'svg', 'geojson' or 'yaml'")
+	mapsExportCmd.Flags().StringVarP(&flagMapsExportOutput, "output", "o", "", "File to save the export to, by default stdout")
+}
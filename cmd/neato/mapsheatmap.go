@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/insomniacslk/neato"
+	"github.com/insomniacslk/neato/maps"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagMapsHeatmapSince  string
+	flagMapsHeatmapOutDir string
+)
+
+var mapsHeatmapCmd = &cobra.Command{
+	Use:   "heatmap",
+	Short: "Build a coverage heatmap of the runs of every robot over each persistent map",
+	Run: func(cmd *cobra.Command, args []string) {
+		since, err := parseSince(flagMapsHeatmapSince)
+		if err != nil {
+			log.Fatalf("Invalid --since: %v", err)
+		}
+		acc, err := getAccount()
+		if err != nil {
+			log.Fatalf("Account lookup failed: %v", err)
+		}
+		robots, err := acc.Robots()
+		if err != nil {
+			log.Fatalf("Cannot get robots: %v", err)
+		}
+		if len(robots) == 0 {
+			fmt.Println("No robots found")
+			return
+		}
+		if err := os.MkdirAll(flagMapsHeatmapOutDir, 0o755); err != nil {
+			log.Fatalf("Failed to create output directory: %v", err)
+		}
+		cache := neato.NewMapCache(mapCacheDir())
+		for _, r := range robots {
+			robotMaps, err := r.Maps()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to get maps for robot '%s' (serial: '%s'): %v\n", r.Name, r.Serial, err)
+				continue
+			}
+			var recent []*neato.Map
+			for _, m := range robotMaps {
+				start, err := m.StartTime()
+				if err != nil || start.IsZero() || start.Before(since) {
+					continue
+				}
+				recent = append(recent, m)
+			}
+			for persistentMapID, group := range maps.GroupByPersistentMap(recent) {
+				h := maps.NewHeatmap()
+				for _, m := range group {
+					img, err := loadMapImage(cache, m)
+					if err != nil {
+						fmt.Fprintf(os.Stderr, "Skipping map '%s' of robot '%s': %v\n", m.ID, r.Name, err)
+						continue
+					}
+					h.Add(img)
+				}
+				if h.Runs() == 0 {
+					continue
+				}
+				dst := path.Join(flagMapsHeatmapOutDir, fmt.Sprintf("heatmap-%s-%s.png", r.Serial, persistentMapID))
+				if err := writeHeatmap(h, dst); err != nil {
+					log.Fatalf("Failed to write heatmap: %v", err)
+				}
+				log.Printf("Saved heatmap of %d runs of robot '%s' over persistent map '%s' to '%s'", h.Runs(), r.Name, persistentMapID, dst)
+			}
+		}
+	},
+}
+
+func writeHeatmap(h *maps.Heatmap, dst string) error {
+	fd, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if err := h.WritePNG(fd); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+// parseSince parses a duration relative to now, e.g. "30d" or "12h", and
+// returns the corresponding point in time.
+func parseSince(s string) (time.Time, error) {
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid number of days '%s'", s)
+		}
+		return time.Now().AddDate(0, 0, -days), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(-d), nil
+}
+
+func initMapsHeatmapCmd() {
+	mapsHeatmapCmd.Flags().StringVarP(&flagMapsHeatmapSince, "since", "s", "30d", "Only use runs started within this time, e.g. 30d or 12h")
+	mapsHeatmapCmd.Flags().StringVarP(&flagMapsHeatmapOutDir, "output", "o", ".", "Directory to save the heatmaps to")
+}
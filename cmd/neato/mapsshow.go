@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	_ "image/png"
+	"log"
+	"os"
+
+	"github.com/insomniacslk/neato"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagMapsShowWidth int
+	flagMapsShowSixel bool
+)
+
+var mapsShowCmd = &cobra.Command{
+	Use:   "show [map-id]",
+	Short: "Render a map in the terminal, by default the most recent map of every robot",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		acc, err := getAccount()
+		if err != nil {
+			log.Fatalf("Account lookup failed: %v", err)
+		}
+		robots, err := acc.Robots()
+		if err != nil {
+			log.Fatalf("Cannot get robots: %v", err)
+		}
+		if len(robots) == 0 {
+			fmt.Println("No robots found")
+			return
+		}
+		cache := neato.NewMapCache(mapCacheDir())
+		found := false
+		for _, r := range robots {
+			maps, err := r.Maps()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to get maps for robot '%s' (serial: '%s'): %v\n", r.Name, r.Serial, err)
+				continue
+			}
+			for _, m := range maps {
+				if len(args) > 0 && m.ID != args[0] {
+					continue
+				}
+				found = true
+				if err := showMap(cache, r, m); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to show map '%s' for robot '%s': %v\n", m.ID, r.Name, err)
+				}
+				// without a map ID, only show the most recent map
+				break
+			}
+		}
+		if len(args) > 0 && !found {
+			log.Fatalf("Map '%s' not found", args[0])
+		}
+	},
+}
+
+// loadMapImage returns the decoded image of m, downloading it into the cache
+// if needed.
+func loadMapImage(cache *neato.MapCache, m *neato.Map) (image.Image, error) {
+	p, err := cache.Fetch(context.Background(), m)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	img, _, err := image.Decode(fd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode map image: %w", err)
+	}
+	return img, nil
+}
+
+func showMap(cache *neato.MapCache, r *neato.Robot, m *neato.Map) error {
+	img, err := loadMapImage(cache, m)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Robot '%s' (serial: '%s')\n", r.Name, r.Serial)
+	fmt.Printf("  %s\n", m)
+	if start, err := m.StartTime(); err == nil && !start.IsZero() {
+		if local, err := r.LocalTime(start); err == nil {
+			start = local
+		}
+		fmt.Printf("  Started: %s", start.Format("2006-01-02 15:04 MST"))
+		if d, err := m.RunDuration(); err == nil {
+			fmt.Printf(", duration: %s", d)
+		}
+		fmt.Println()
+	}
+	if m.CleanedArea != nil {
+		fmt.Printf("  Cleaned area: %.2f sqm\n", *m.CleanedArea)
+	}
+	fmt.Printf("  Dock marker (top right): %s (end orientation %d°)", orientationArrow(m.EndOrientationRelativeDegrees), m.EndOrientationRelativeDegrees)
+	if m.IsDocked != nil {
+		fmt.Printf(", docked at end: %v", *m.IsDocked)
+	}
+	fmt.Println()
+
+	docked := m.IsDocked != nil && *m.IsDocked
+	if flagMapsShowSixel {
+		scaled := scaleImage(img, flagMapsShowWidth*8, 6)
+		drawDockMarker(scaled, m.EndOrientationRelativeDegrees, docked)
+		return renderSixel(os.Stdout, scaled)
+	}
+	scaled := scaleImage(img, flagMapsShowWidth, 2)
+	drawDockMarker(scaled, m.EndOrientationRelativeDegrees, docked)
+	return renderHalfBlocks(os.Stdout, scaled)
+}
+
+func initMapsShowCmd() {
+	mapsShowCmd.Flags().IntVarP(&flagMapsShowWidth, "width", "w", 80, "Width of the rendered map, in terminal columns")
+	mapsShowCmd.Flags().BoolVarP(&flagMapsShowSixel, "sixel", "s", false, "Render the map as Sixel graphics instead of Unicode half blocks")
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+)
+
+// scaleImage resizes img to the given width, keeping the aspect ratio, by
+// averaging the source pixels covered by every destination pixel.
+// heightMultiple rounds the height up to a multiple of the given value, the
+// extra rows are transparent. An empty image scales to an empty image.
+func scaleImage(img image.Image, width, heightMultiple int) *image.NRGBA {
+	b := img.Bounds()
+	if b.Dx() <= 0 || b.Dy() <= 0 {
+		return image.NewNRGBA(image.Rect(0, 0, 0, 0))
+	}
+	if width <= 0 || width > b.Dx() {
+		width = b.Dx()
+	}
+	height := b.Dy() * width / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	padded := height
+	if rem := padded % heightMultiple; rem != 0 {
+		padded += heightMultiple - rem
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, width, padded))
+	for y := 0; y < height; y++ {
+		sy0 := b.Min.Y + y*b.Dy()/height
+		sy1 := b.Min.Y + (y+1)*b.Dy()/height
+		if sy1 == sy0 {
+			sy1++
+		}
+		for x := 0; x < width; x++ {
+			sx0 := b.Min.X + x*b.Dx()/width
+			sx1 := b.Min.X + (x+1)*b.Dx()/width
+			if sx1 == sx0 {
+				sx1++
+			}
+			var r, g, bl, a, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					c := color.NRGBAModel.Convert(img.At(sx, sy)).(color.NRGBA)
+					r += uint32(c.R)
+					g += uint32(c.G)
+					bl += uint32(c.B)
+					a += uint32(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(bl / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// renderHalfBlocks writes img to w using the Unicode upper half block, so
+// that every character cell represents two vertically adjacent pixels. Fully
+// transparent pixels use the terminal's background. The height of img should
+// be even, see scaleImage.
+func renderHalfBlocks(w io.Writer, scaled *image.NRGBA) error {
+	b := scaled.Bounds()
+	var sb strings.Builder
+	for y := b.Min.Y; y < b.Max.Y; y += 2 {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			top := scaled.NRGBAAt(x, y)
+			bottom := scaled.NRGBAAt(x, y+1)
+			switch {
+			case top.A == 0 && bottom.A == 0:
+				sb.WriteString("\x1b[0m ")
+			case bottom.A == 0:
+				fmt.Fprintf(&sb, "\x1b[0m\x1b[38;2;%d;%d;%dm▀", top.R, top.G, top.B)
+			case top.A == 0:
+				fmt.Fprintf(&sb, "\x1b[0m\x1b[38;2;%d;%d;%dm▄", bottom.R, bottom.G, bottom.B)
+			default:
+				fmt.Fprintf(&sb, "\x1b[38;2;%d;%d;%dm\x1b[48;2;%d;%d;%dm▀", top.R, top.G, top.B, bottom.R, bottom.G, bottom.B)
+			}
+		}
+		sb.WriteString("\x1b[0m\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// sixelColor quantizes a color to the 6x6x6 color cube, returning its
+// palette index.
+func sixelColor(c color.NRGBA) int {
+	q := func(v uint8) int { return (int(v)*5 + 127) / 255 }
+	return q(c.R)*36 + q(c.G)*6 + q(c.B)
+}
+
+// renderSixel writes img to w as a Sixel graphic, for terminals that support
+// it. Colors are quantized to a 216-color palette. The height of img should
+// be a multiple of 6, see scaleImage.
+func renderSixel(w io.Writer, scaled *image.NRGBA) error {
+	b := scaled.Bounds()
+	var sb strings.Builder
+	// DCS, 1:1 pixel aspect ratio, transparent background
+	sb.WriteString("\x1bP0;1;0q")
+	fmt.Fprintf(&sb, "\"1;1;%d;%d", b.Dx(), b.Dy())
+	for i := 0; i < 216; i++ {
+		r, g, bl := i/36, (i/6)%6, i%6
+		fmt.Fprintf(&sb, "#%d;2;%d;%d;%d", i, r*20, g*20, bl*20)
+	}
+	for y := b.Min.Y; y < b.Max.Y; y += 6 {
+		// bitmaps of the six rows of this band, per color
+		bands := make(map[int][]byte)
+		var order []int
+		for x := b.Min.X; x < b.Max.X; x++ {
+			for dy := 0; dy < 6; dy++ {
+				c := scaled.NRGBAAt(x, y+dy)
+				if c.A < 128 {
+					continue
+				}
+				idx := sixelColor(c)
+				row, ok := bands[idx]
+				if !ok {
+					row = make([]byte, b.Dx())
+					bands[idx] = row
+					order = append(order, idx)
+				}
+				row[x-b.Min.X] |= 1 << dy
+			}
+		}
+		for i, idx := range order {
+			if i > 0 {
+				sb.WriteByte('$')
+			}
+			fmt.Fprintf(&sb, "#%d", idx)
+			writeSixelRow(&sb, bands[idx])
+		}
+		sb.WriteByte('-')
+	}
+	sb.WriteString("\x1b\\\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// writeSixelRow writes one band of sixels, run-length encoded.
+func writeSixelRow(sb *strings.Builder, row []byte) {
+	for i := 0; i < len(row); {
+		j := i
+		for j < len(row) && row[j] == row[i] {
+			j++
+		}
+		ch := byte(63 + row[i])
+		if n := j - i; n > 3 {
+			fmt.Fprintf(sb, "!%d%c", n, ch)
+		} else {
+			for k := 0; k < n; k++ {
+				sb.WriteByte(ch)
+			}
+		}
+		i = j
+	}
+}
+
+var (
+	dockMarkerDocked   = color.NRGBA{40, 180, 40, 255}
+	dockMarkerUndocked = color.NRGBA{230, 150, 20, 255}
+	dockMarkerArrow    = color.NRGBA{255, 255, 255, 255}
+)
+
+// drawDockMarker draws a dock marker in the top-right corner of img: a disc,
+// green if the robot ended the run docked and amber otherwise, with an arrow
+// pointing to the end orientation relative to the dock, in degrees, where 0
+// points up and angles grow clockwise. The map endpoint does not report where
+// the dock is on the image, so the marker does not pretend to be on it.
+func drawDockMarker(img *image.NRGBA, degrees int, docked bool) {
+	b := img.Bounds()
+	radius := b.Dx() / 16
+	if h := b.Dy() / 8; h < radius {
+		radius = h
+	}
+	if radius < 3 {
+		if b.Dx() < 7 || b.Dy() < 7 {
+			return
+		}
+		radius = 3
+	}
+	cx, cy := b.Max.X-radius-2, b.Min.Y+radius+1
+	disc := dockMarkerUndocked
+	if docked {
+		disc = dockMarkerDocked
+	}
+	for y := -radius; y <= radius; y++ {
+		for x := -radius; x <= radius; x++ {
+			if x*x+y*y <= radius*radius {
+				img.SetNRGBA(cx+x, cy+y, disc)
+			}
+		}
+	}
+	rad := float64(degrees) * math.Pi / 180
+	dx, dy := math.Sin(rad), -math.Cos(rad)
+	for i := 0; i < radius; i++ {
+		img.SetNRGBA(cx+int(math.Round(dx*float64(i))), cy+int(math.Round(dy*float64(i))), dockMarkerArrow)
+	}
+}
+
+// orientationArrow returns the arrow pointing closest to the given angle,
+// in degrees, where 0 points up and angles grow clockwise.
+func orientationArrow(degrees int) string {
+	arrows := []string{"↑", "↗", "→", "↘", "↓", "↙", "←", "↖"}
+	idx := ((degrees%360+360)%360 + 22) / 45 % 8
+	return arrows[idx]
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestScaleImageEmpty(t *testing.T) {
+	for _, r := range []image.Rectangle{image.Rect(0, 0, 0, 0), image.Rect(0, 0, 10, 0), image.Rect(0, 0, 0, 10)} {
+		scaled := scaleImage(image.NewNRGBA(r), 80, 2)
+		if !scaled.Bounds().Empty() {
+			t.Errorf("%v: got bounds %v, want empty", r, scaled.Bounds())
+		}
+		var buf bytes.Buffer
+		if err := renderHalfBlocks(&buf, scaled); err != nil {
+			t.Errorf("%v: %v", r, err)
+		}
+	}
+}
+
+func TestScaleImagePadding(t *testing.T) {
+	scaled := scaleImage(image.NewNRGBA(image.Rect(0, 0, 100, 50)), 10, 6)
+	if got := scaled.Bounds().Size(); got != (image.Point{10, 6}) {
+		t.Errorf("got size %v, want 10x6", got)
+	}
+}
+
+func TestDrawDockMarker(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 80, 40))
+	drawDockMarker(img, 90, true)
+	// radius is 40/8 = 5, centered 2 pixels from the right edge
+	cx, cy := 80-5-2, 5+1
+	if got := img.NRGBAAt(cx-3, cy); got != dockMarkerDocked {
+		t.Errorf("disc: got %v, want %v", got, dockMarkerDocked)
+	}
+	// 90 degrees points right
+	if got := img.NRGBAAt(cx+3, cy); got != dockMarkerArrow {
+		t.Errorf("arrow: got %v, want %v", got, dockMarkerArrow)
+	}
+	if got := img.NRGBAAt(0, 39); got.A != 0 {
+		t.Errorf("the rest of the image should be untouched, got %v", got)
+	}
+
+	// too small to draw anything, must not panic
+	drawDockMarker(image.NewNRGBA(image.Rect(0, 0, 4, 4)), 0, false)
+}
+
+func TestOrientationArrow(t *testing.T) {
+	for degrees, want := range map[int]string{0: "↑", 44: "↗", 90: "→", 180: "↓", -90: "←", 359: "↑", 720: "↑"} {
+		if got := orientationArrow(degrees); got != want {
+			t.Errorf("%d: got %s, want %s", degrees, got, want)
+		}
+	}
+}
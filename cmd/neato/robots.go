@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"os"
+	"sync"
 
+	"github.com/insomniacslk/neato"
 	"github.com/spf13/cobra"
 )
 
@@ -24,15 +28,44 @@ var robotsCmd = &cobra.Command{
 			fmt.Println("No robots found")
 			return
 		}
+		var mu sync.Mutex
+		online := make(map[string]bool)
+		errs, err := acc.ForEachRobot(context.Background(), flagConcurrency, func(ctx context.Context, r *neato.Robot) error {
+			ok, err := r.Online(ctx)
+			mu.Lock()
+			online[r.Serial] = ok
+			mu.Unlock()
+			return err
+		})
+		if err != nil {
+			log.Fatalf("Cannot check whether robots are online: %v", err)
+		}
+		for serial, err := range errs {
+			fmt.Fprintf(os.Stderr, "Failed to check whether robot '%s' is online: %v\n", serial, err)
+		}
 		if flagJSON {
-			j, err := json.Marshal(robots)
+			type robotWithStatus struct {
+				*neato.Robot
+				Online bool `json:"online"`
+			}
+			out := make([]robotWithStatus, 0, len(robots))
+			for _, r := range robots {
+				out = append(out, robotWithStatus{Robot: r, Online: online[r.Serial]})
+			}
+			j, err := json.Marshal(out)
 			if err != nil {
 				log.Fatalf("Failed to marshal to JSON: %v", err)
 			}
 			fmt.Println(string(j))
 		} else {
 			for idx, r := range robots {
-				fmt.Printf("%d) %s\n", idx+1, r)
+				status := "online"
+				if _, failed := errs[r.Serial]; failed {
+					status = "unknown"
+				} else if !online[r.Serial] {
+					status = "offline"
+				}
+				fmt.Printf("%d) %s, Online: %s\n", idx+1, r, status)
 			}
 		}
 	},
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/insomniacslk/neato"
+	"github.com/spf13/cobra"
+)
+
+var roomsCmd = &cobra.Command{
+	Use:   "rooms",
+	Short: "Show the named rooms that can be cleaned with the clean command",
+	Run: func(cmd *cobra.Command, args []string) {
+		reg, err := neato.LoadRooms()
+		if err != nil {
+			log.Fatalf("Cannot load rooms: %v", err)
+		}
+		rooms := reg.Rooms()
+		if flagJSON {
+			j, err := json.Marshal(rooms)
+			if err != nil {
+				log.Fatalf("Failed to marshal to JSON: %v", err)
+			}
+			fmt.Println(string(j))
+			return
+		}
+		if len(rooms) == 0 {
+			fmt.Println("No rooms found, run `neato rooms discover` or `neato rooms add`")
+			return
+		}
+		for idx, r := range rooms {
+			fmt.Printf("%d) %s\n", idx+1, r)
+		}
+	},
+}
+
+var roomsDiscoverCmd = &cobra.Command{
+	Use:   "discover",
+	Short: "Add a room for every named zone of the persistent maps of every robot",
+	Run: func(cmd *cobra.Command, args []string) {
+		acc, err := getAccount()
+		if err != nil {
+			log.Fatalf("Account lookup failed: %v", err)
+		}
+		reg, err := neato.LoadRooms()
+		if err != nil {
+			log.Fatalf("Cannot load rooms: %v", err)
+		}
+		added, err := reg.Discover(context.Background(), acc)
+		if err != nil {
+			// rooms of the other robots are still saved
+			fmt.Fprintf(os.Stderr, "Room discovery failed for some robots: %v\n", err)
+		}
+		if err := reg.Save(); err != nil {
+			log.Fatalf("Failed to save rooms: %v", err)
+		}
+		log.Printf("Added %d rooms", added)
+	},
+}
+
+var roomsAddCmd = &cobra.Command{
+	Use:   "add <name> <serial> <persistent-map-id> <boundary-id>",
+	Short: "Add or replace a room",
+	Args:  cobra.ExactArgs(4),
+	Run: func(cmd *cobra.Command, args []string) {
+		reg, err := neato.LoadRooms()
+		if err != nil {
+			log.Fatalf("Cannot load rooms: %v", err)
+		}
+		room := neato.Room{
+			Name:            args[0],
+			RobotSerial:     args[1],
+			PersistentMapID: args[2],
+			BoundaryID:      args[3],
+		}
+		if !reg.Add(&room) {
+			log.Fatalf("Invalid room name '%s'", args[0])
+		}
+		if err := reg.Save(); err != nil {
+			log.Fatalf("Failed to save rooms: %v", err)
+		}
+	},
+}
+
+var roomsRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a room",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		reg, err := neato.LoadRooms()
+		if err != nil {
+			log.Fatalf("Cannot load rooms: %v", err)
+		}
+		if !reg.Remove(args[0]) {
+			log.Fatalf("Room '%s' not found", args[0])
+		}
+		if err := reg.Save(); err != nil {
+			log.Fatalf("Failed to save rooms: %v", err)
+		}
+	},
+}
+
+// lookupRoom resolves a room name, discovering rooms from the robots' zones
+// if it is not in the registry yet.
+func lookupRoom(acc *neato.Account, name string) (*neato.Room, error) {
+	reg, err := neato.LoadRooms()
+	if err != nil {
+		return nil, err
+	}
+	if room, ok := reg.Lookup(name); ok {
+		return room, nil
+	}
+	_, discoverErr := reg.Discover(context.Background(), acc)
+	if err := reg.Save(); err != nil {
+		return nil, err
+	}
+	if room, ok := reg.Lookup(name); ok {
+		return room, nil
+	}
+	if discoverErr != nil {
+		return nil, fmt.Errorf("room '%s' not found, and room discovery failed: %w", name, discoverErr)
+	}
+	return nil, fmt.Errorf("room '%s' not found", name)
+}
+
+func initRoomsCmd() {
+	roomsCmd.AddCommand(roomsDiscoverCmd)
+	roomsCmd.AddCommand(roomsAddCmd)
+	roomsCmd.AddCommand(roomsRemoveCmd)
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/insomniacslk/neato"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagServeListen   string
+	flagServeInterval time.Duration
+	flagServeAPIToken string
+	flagServeOrigins  []string
+)
+
+// shutdownTimeout is how long in-flight requests are given to complete when
+// the daemon is stopped.
+const shutdownTimeout = 10 * time.Second
+
+// runMapTimeout bounds the map lookup of a run_completed event.
+const runMapTimeout = time.Minute
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run a daemon that exposes the robots over a local REST API",
+	Long: `Run a daemon that polls every robot and exposes them over a local REST API:
+
+  GET  /robots                   list the robots with their last known state
+  GET  /robots/{serial}/state    state of a robot
+  POST /robots/{serial}/start    start cleaning, optionally with a JSON body
+                                 like {"room": "kitchen", "mode": "turbo"}
+  POST /robots/{serial}/stop     stop cleaning
+  POST /robots/{serial}/pause    pause cleaning
+  POST /robots/{serial}/resume   resume cleaning
+  POST /robots/{serial}/dock     send the robot back to its base
+  GET  /events                   stream robot events as Server-Sent Events
+  GET  /events/ws                stream robot events over a WebSocket
+  GET  /metrics                  robot and API metrics in the Prometheus format
+
+Event streams accept an optional ?serial= parameter to only follow one robot.
+
+The API is meant for local clients, not for web pages: requests with an
+Origin header are rejected, except for the event streams opened from an
+origin given with --allow-origin, and so are POST requests with a body that
+is not application/json. With --api-token, every request must also carry the
+token as "Authorization: Bearer <token>".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if flagServeInterval <= 0 {
+			log.Fatalf("Invalid --interval %s, must be positive", flagServeInterval)
+		}
+		metrics := newAPIMetrics()
+		neato.APICallHook = metrics.observe
+		acc, err := getAccount()
+		if err != nil {
+			log.Fatalf("Account lookup failed: %v", err)
+		}
+		robots, err := acc.Robots()
+		if err != nil {
+			log.Fatalf("Cannot get robots: %v", err)
+		}
+		rooms, err := neato.LoadRooms()
+		if err != nil {
+			log.Fatalf("Cannot load rooms: %v", err)
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer cancel()
+
+		d := newDaemon(robots, rooms, metrics)
+		d.token = flagServeAPIToken
+		d.allowedOrigins = flagServeOrigins
+		var wg sync.WaitGroup
+		for _, r := range robots {
+			events := r.Watch(ctx, flagServeInterval)
+			wg.Add(1)
+			go func(events <-chan neato.StateEvent) {
+				defer wg.Done()
+				for ev := range events {
+					d.handleEvent(ev)
+				}
+			}(events)
+		}
+
+		srv := &http.Server{Addr: flagServeListen, Handler: d.handler()}
+		shutdownDone := make(chan struct{})
+		go func() {
+			defer close(shutdownDone)
+			<-ctx.Done()
+			log.Printf("Shutting down")
+			d.shutdown()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				log.Printf("Failed to shut down cleanly: %v", err)
+			}
+		}()
+		log.Printf("Serving %d robot(s) on http://%s", len(robots), flagServeListen)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Server failed: %v", err)
+		}
+		<-shutdownDone
+		wg.Wait()
+	},
+}
+
+func initServeCmd() {
+	serveCmd.Flags().StringVarP(&flagServeListen, "listen", "l", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().DurationVarP(&flagServeInterval, "interval", "i", 15*time.Second, "How often to poll every robot")
+	serveCmd.Flags().StringVarP(&flagServeAPIToken, "api-token", "", "", "Bearer token required by every request, none if empty")
+	serveCmd.Flags().StringSliceVarP(&flagServeOrigins, "allow-origin", "", nil, "Origin of a web page allowed to follow the event streams, like http://localhost:3000, may be repeated")
+}
+
+// robotStatus is the outcome of the last poll of a robot.
+type robotStatus struct {
+	State   *neato.RobotState
+	Updated time.Time
+	Err     error
+}
+
+type daemon struct {
+	robots   []*neato.Robot
+	bySerial map[string]*neato.Robot
+	rooms    *neato.RoomRegistry
+	events   *eventHub
+	metrics  *apiMetrics
+	// token, if not empty, is the bearer token required by every request.
+	token string
+	// allowedOrigins are the origins of the web pages that may follow the
+	// event streams.
+	allowedOrigins []string
+
+	mu     sync.RWMutex
+	status map[string]*robotStatus
+	// runs holds the start time of the ongoing run of each robot, zero if
+	// it started before the daemon.
+	runs map[string]time.Time
+	// closing is set by shutdown, after which no background work starts.
+	closing bool
+
+	// background tracks the work started by events, like run_completed
+	// lookups, which is canceled by shutdown.
+	background       sync.WaitGroup
+	backgroundCtx    context.Context
+	cancelBackground context.CancelFunc
+}
+
+func newDaemon(robots []*neato.Robot, rooms *neato.RoomRegistry, metrics *apiMetrics) *daemon {
+	d := daemon{
+		robots:   robots,
+		bySerial: make(map[string]*neato.Robot),
+		rooms:    rooms,
+		events:   newEventHub(),
+		metrics:  metrics,
+		status:   make(map[string]*robotStatus),
+		runs:     make(map[string]time.Time),
+	}
+	d.backgroundCtx, d.cancelBackground = context.WithCancel(context.Background())
+	for _, r := range robots {
+		d.bySerial[r.Serial] = r
+	}
+	return &d
+}
+
+// goBackground runs fn in a goroutine tracked by shutdown, unless the daemon
+// is shutting down. It must be called with d.mu held.
+func (d *daemon) goBackground(fn func(ctx context.Context)) {
+	if d.closing {
+		return
+	}
+	d.background.Add(1)
+	go func() {
+		defer d.background.Done()
+		fn(d.backgroundCtx)
+	}()
+}
+
+// shutdown cancels and waits for the background work, then ends the event
+// streams.
+func (d *daemon) shutdown() {
+	d.mu.Lock()
+	d.closing = true
+	d.mu.Unlock()
+	d.cancelBackground()
+	d.background.Wait()
+	d.events.close()
+}
+
+// handleEvent records the state carried by a watcher event and publishes it
+// to the event streams.
+func (d *daemon) handleEvent(ev neato.StateEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	st := robotStatus{Updated: ev.Time, Err: ev.Err}
+	if ev.Kind == neato.StateEventPollError {
+		if prev, ok := d.status[ev.Serial]; ok {
+			st.State = prev.State
+		}
+	} else {
+		st.State = ev.Current
+	}
+	d.status[ev.Serial] = &st
+	if ev.Kind == neato.StateEventPolled {
+		// only refreshes the status, it is not a robot event
+		return
+	}
+	d.events.publish(newAPIEvent(ev))
+	d.trackRun(ev)
+}
+
+func (d *daemon) lastStatus(serial string) (robotStatus, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	st, ok := d.status[serial]
+	if !ok {
+		return robotStatus{}, false
+	}
+	return *st, true
+}
+
+func (d *daemon) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots", d.handleRobots)
+	mux.HandleFunc("/robots/", d.handleRobot)
+	mux.HandleFunc("/events", d.handleEvents)
+	mux.HandleFunc("/events/ws", d.handleEventsWebSocket)
+	mux.HandleFunc("/metrics", d.handleMetrics)
+	h := d.checkRequest(mux)
+	if !flagDebug {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+		h.ServeHTTP(w, r)
+	})
+}
+
+// checkRequest rejects the requests that a web page could send on behalf of
+// the user, so that no site can start a robot, and those without the bearer
+// token, if one is set.
+func (d *daemon) checkRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !d.originAllowed(req) {
+			writeAPIError(w, http.StatusForbidden, fmt.Errorf("requests from origin '%s' are not allowed", req.Header.Get("Origin")))
+			return
+		}
+		if d.token != "" && !hasBearerToken(req, d.token) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		if req.Method == http.MethodPost {
+			if err := checkContentType(req); err != nil {
+				writeAPIError(w, http.StatusUnsupportedMediaType, err)
+				return
+			}
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// originAllowed reports whether a web page may send req. Only the event
+// streams may be followed across origins, and only from allowedOrigins.
+func (d *daemon) originAllowed(req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if req.Method != http.MethodGet || (req.URL.Path != "/events" && req.URL.Path != "/events/ws") {
+		return false
+	}
+	for _, o := range d.allowedOrigins {
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+func hasBearerToken(req *http.Request, token string) bool {
+	auth := req.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(auth[len(prefix):]), []byte(token)) == 1
+}
+
+// checkContentType only accepts JSON bodies. Forms, which browsers send
+// across origins without asking, are not.
+func checkContentType(req *http.Request) error {
+	ct := req.Header.Get("Content-Type")
+	if ct == "" {
+		if req.ContentLength != 0 {
+			return errors.New("a request body requires the application/json Content-Type")
+		}
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil || mediaType != "application/json" {
+		return fmt.Errorf("unsupported Content-Type '%s', must be application/json", ct)
+	}
+	return nil
+}
+
+// robotInfo is the representation of a robot in GET /robots.
+type robotInfo struct {
+	Serial  string            `json:"serial"`
+	Name    string            `json:"name"`
+	Model   *string           `json:"model"`
+	Online  bool              `json:"online"`
+	Updated *time.Time        `json:"updated,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	State   *neato.RobotState `json:"state,omitempty"`
+}
+
+func (d *daemon) handleRobots(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method))
+		return
+	}
+	infos := make([]robotInfo, 0, len(d.robots))
+	for _, r := range d.robots {
+		info := robotInfo{Serial: r.Serial, Name: r.Name, Model: r.Model}
+		if st, ok := d.lastStatus(r.Serial); ok {
+			updated := st.Updated
+			info.Updated = &updated
+			info.State = st.State
+			info.Online = st.Err == nil
+			if st.Err != nil {
+				info.Error = st.Err.Error()
+			}
+		}
+		infos = append(infos, info)
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+// handleRobot serves /robots/{serial}/{action}.
+func (d *daemon) handleRobot(w http.ResponseWriter, req *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(req.URL.Path, "/robots/"), "/")
+	if len(parts) != 2 {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("no such endpoint '%s'", req.URL.Path))
+		return
+	}
+	serial, action := parts[0], parts[1]
+	robot, ok := d.bySerial[serial]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("robot with serial '%s' not found", serial))
+		return
+	}
+
+	if action == "state" {
+		if req.Method != http.MethodGet {
+			writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method))
+			return
+		}
+		if st, ok := d.lastStatus(serial); ok && st.Err == nil && st.State != nil {
+			writeJSON(w, http.StatusOK, st.State)
+			return
+		}
+		state, err := robot.State(req.Context())
+		if err != nil {
+			writeRobotError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, state)
+		return
+	}
+
+	commands := map[string]func(context.Context) error{
+		"stop":   robot.Stop,
+		"pause":  robot.Pause,
+		"resume": robot.Resume,
+		"dock":   robot.SendToBase,
+		"start": func(ctx context.Context) error {
+			opts, err := d.startOptions(req)
+			if err != nil {
+				return err
+			}
+			return robot.Start(ctx, opts)
+		},
+	}
+	command, ok := commands[action]
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("no such endpoint '%s'", req.URL.Path))
+		return
+	}
+	if req.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method))
+		return
+	}
+	if err := command(req.Context()); err != nil {
+		writeRobotError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"result": "ok"})
+}
+
+// startRequest is the optional body of POST /robots/{serial}/start.
+type startRequest struct {
+	Room           string                `json:"room"`
+	Mode           *neato.CleaningMode   `json:"mode"`
+	NavigationMode *neato.NavigationMode `json:"navigation_mode"`
+	MapID          string                `json:"map_id"`
+	BoundaryID     string                `json:"boundary_id"`
+}
+
+// badRequestError marks errors in the request, as opposed to errors from the
+// robot.
+type badRequestError struct {
+	err error
+}
+
+func (e *badRequestError) Error() string { return e.err.Error() }
+func (e *badRequestError) Unwrap() error { return e.err }
+
+func (d *daemon) startOptions(req *http.Request) (*neato.CleaningOptions, error) {
+	var body startRequest
+	if req.ContentLength != 0 {
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, &badRequestError{fmt.Errorf("invalid request body: %w", err)}
+		}
+	}
+	opts := neato.NewCleaningOptions()
+	if body.Room != "" {
+		room, ok := d.rooms.Lookup(body.Room)
+		if !ok {
+			return nil, &badRequestError{fmt.Errorf("room '%s' not found", body.Room)}
+		}
+		opts = room.CleaningOptions()
+	} else if body.MapID != "" {
+		opts.Category = &neato.CategoryPersistentMap
+		opts.MapID = body.MapID
+		opts.BoundaryID = body.BoundaryID
+	}
+	if body.Mode != nil {
+		opts.CleaningMode = *body.Mode
+	}
+	if body.NavigationMode != nil {
+		opts.NavigationMode = *body.NavigationMode
+	}
+	return opts, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeRobotError maps the errors of robot commands to HTTP statuses.
+func writeRobotError(w http.ResponseWriter, err error) {
+	var (
+		badRequest *badRequestError
+		result     *neato.ResultError
+	)
+	switch {
+	case errors.As(err, &badRequest):
+		writeAPIError(w, http.StatusBadRequest, err)
+	case errors.Is(err, neato.ErrRobotOffline):
+		writeAPIError(w, http.StatusServiceUnavailable, err)
+	case errors.As(err, &result):
+		writeAPIError(w, http.StatusConflict, err)
+	default:
+		writeAPIError(w, http.StatusBadGateway, err)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/neato"
+)
+
+// newTestDaemon returns a daemon for a robot with serial "serial-1", idle
+// since its last poll, and a "kitchen" room.
+func newTestDaemon(t *testing.T) *daemon {
+	rooms := neato.NewRoomRegistry()
+	rooms.Add(&neato.Room{Name: "kitchen", RobotSerial: "serial-1", PersistentMapID: "map-1", BoundaryID: "boundary-1"})
+	d := newDaemon([]*neato.Robot{{Serial: "serial-1", Name: "robot"}}, rooms, newAPIMetrics())
+	d.handleEvent(neato.StateEvent{
+		Kind:    neato.StateEventInitial,
+		Time:    time.Now(),
+		Serial:  "serial-1",
+		Current: &neato.RobotState{State: neato.StateIdle},
+	})
+	t.Cleanup(d.shutdown)
+	return d
+}
+
+func TestDaemonRouting(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		method     string
+		path       string
+		header     map[string]string
+		body       string
+		token      string
+		wantStatus int
+	}{
+		{name: "robots", method: http.MethodGet, path: "/robots", wantStatus: http.StatusOK},
+		{name: "robots wrong method", method: http.MethodPost, path: "/robots", wantStatus: http.StatusMethodNotAllowed},
+		{name: "cached state", method: http.MethodGet, path: "/robots/serial-1/state", wantStatus: http.StatusOK},
+		{name: "unknown robot", method: http.MethodGet, path: "/robots/serial-2/state", wantStatus: http.StatusNotFound},
+		{name: "unknown action", method: http.MethodPost, path: "/robots/serial-1/vacuum", wantStatus: http.StatusNotFound},
+		{name: "too deep", method: http.MethodGet, path: "/robots/serial-1/state/more", wantStatus: http.StatusNotFound},
+		{name: "command wrong method", method: http.MethodGet, path: "/robots/serial-1/stop", wantStatus: http.StatusMethodNotAllowed},
+		{
+			name: "start unknown room", method: http.MethodPost, path: "/robots/serial-1/start",
+			header: map[string]string{"Content-Type": "application/json"}, body: `{"room":"attic"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "cross-origin", method: http.MethodGet, path: "/robots",
+			header: map[string]string{"Origin": "https://example.com"}, wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "form body", method: http.MethodPost, path: "/robots/serial-1/start",
+			header: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, body: "room=kitchen",
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name: "body without content type", method: http.MethodPost, path: "/robots/serial-1/start",
+			body: `{"room":"kitchen"}`, wantStatus: http.StatusUnsupportedMediaType,
+		},
+		{name: "missing token", method: http.MethodGet, path: "/robots", token: "secret", wantStatus: http.StatusUnauthorized},
+		{
+			name: "wrong token", method: http.MethodGet, path: "/robots", token: "secret",
+			header: map[string]string{"Authorization": "Bearer guess"}, wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "token", method: http.MethodGet, path: "/robots", token: "secret",
+			header: map[string]string{"Authorization": "Bearer secret"}, wantStatus: http.StatusOK,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newTestDaemon(t)
+			d.token = tc.token
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			d.handler().ServeHTTP(w, req)
+			if w.Code != tc.wantStatus {
+				t.Errorf("got status %d, want %d, body: %s", w.Code, tc.wantStatus, w.Body)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("got Content-Type '%s', want application/json", ct)
+			}
+		})
+	}
+}
+
+func TestDaemonRobots(t *testing.T) {
+	d := newTestDaemon(t)
+	w := httptest.NewRecorder()
+	d.handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/robots", nil))
+	var infos []robotInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &infos); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Serial != "serial-1" || !infos[0].Online || infos[0].State == nil || infos[0].State.State != neato.StateIdle {
+		t.Errorf("unexpected robots %s", w.Body)
+	}
+}
+
+func TestStartOptions(t *testing.T) {
+	turbo, deep := neato.CleaningModeTurbo, neato.NavigationModeDeep
+	for _, tc := range []struct {
+		name    string
+		body    string
+		want    neato.CleaningOptions
+		wantErr bool
+	}{
+		{name: "no body", want: *neato.NewCleaningOptions()},
+		{
+			name: "room", body: `{"room":"Kitchen"}`,
+			want: *(&neato.Room{PersistentMapID: "map-1", BoundaryID: "boundary-1"}).CleaningOptions(),
+		},
+		{
+			name: "map", body: `{"map_id":"map-2","boundary_id":"boundary-2"}`,
+			want: neato.CleaningOptions{
+				CleaningMode:   neato.CleaningModeEco,
+				NavigationMode: neato.NavigationModeNormal,
+				Category:       &neato.CategoryPersistentMap,
+				MapID:          "map-2",
+				BoundaryID:     "boundary-2",
+			},
+		},
+		{
+			name: "modes", body: `{"mode":"turbo","navigation_mode":"deep"}`,
+			want: neato.CleaningOptions{CleaningMode: turbo, NavigationMode: deep, Category: &neato.CategoryNonPersistentMap},
+		},
+		{name: "unknown room", body: `{"room":"attic"}`, wantErr: true},
+		{name: "invalid mode", body: `{"mode":"hyper"}`, wantErr: true},
+		{name: "invalid body", body: `{"room":`, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newTestDaemon(t)
+			req := httptest.NewRequest(http.MethodPost, "/robots/serial-1/start", strings.NewReader(tc.body))
+			opts, err := d.startOptions(req)
+			if tc.wantErr {
+				var badRequest *badRequestError
+				if !errors.As(err, &badRequest) {
+					t.Fatalf("got error %v, want a bad request", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := fmt.Sprintf("%+v %v", *opts, *opts.Category), fmt.Sprintf("%+v %v", tc.want, *tc.want.Category); got != want {
+				t.Errorf("got options %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteRobotError(t *testing.T) {
+	for _, tc := range []struct {
+		err        error
+		wantStatus int
+	}{
+		{&badRequestError{errors.New("invalid")}, http.StatusBadRequest},
+		{fmt.Errorf("stop request failed: %w", neato.ErrRobotOffline), http.StatusServiceUnavailable},
+		{fmt.Errorf("stop request failed: %w", &neato.ResultError{Cmd: "stopCleaning", Result: "not_on_charge_base"}), http.StatusConflict},
+		{errors.New("connection refused"), http.StatusBadGateway},
+	} {
+		w := httptest.NewRecorder()
+		writeRobotError(w, tc.err)
+		if w.Code != tc.wantStatus {
+			t.Errorf("got status %d for error '%v', want %d", w.Code, tc.err, tc.wantStatus)
+		}
+		var body map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["error"] != tc.err.Error() {
+			t.Errorf("got body %s for error '%v'", w.Body, tc.err)
+		}
+	}
+}
+
+func TestDaemonShutdownWaitsForBackground(t *testing.T) {
+	d := newDaemon(nil, nil, newAPIMetrics())
+	started := make(chan struct{})
+	finished := make(chan struct{})
+	d.mu.Lock()
+	d.goBackground(func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		close(finished)
+	})
+	d.mu.Unlock()
+	<-started
+
+	done := make(chan struct{})
+	go func() {
+		d.shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("shutdown did not cancel the background work")
+	}
+	select {
+	case <-finished:
+	default:
+		t.Fatal("shutdown returned before the background work finished")
+	}
+
+	d.mu.Lock()
+	d.goBackground(func(context.Context) {
+		t.Error("background work started after shutdown")
+	})
+	d.mu.Unlock()
+	// publishing after shutdown is a no-op
+	d.events.publish(apiEvent{Type: eventTypeRunCompleted})
+}
+
+func TestOriginAllowed(t *testing.T) {
+	d := newTestDaemon(t)
+	d.allowedOrigins = []string{"http://localhost:3000"}
+	for _, tc := range []struct {
+		method string
+		path   string
+		origin string
+		want   bool
+	}{
+		{http.MethodPost, "/robots/serial-1/start", "", true},
+		{http.MethodGet, "/events", "http://localhost:3000", true},
+		{http.MethodGet, "/events/ws", "http://localhost:3000", true},
+		{http.MethodGet, "/events", "https://example.com", false},
+		// allowed origins may only follow the event streams
+		{http.MethodGet, "/robots", "http://localhost:3000", false},
+		{http.MethodPost, "/robots/serial-1/stop", "http://localhost:3000", false},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.origin != "" {
+			req.Header.Set("Origin", tc.origin)
+		}
+		if got := d.originAllowed(req); got != tc.want {
+			t.Errorf("got %v for %s %s from '%s', want %v", got, tc.method, tc.path, tc.origin, tc.want)
+		}
+	}
+}
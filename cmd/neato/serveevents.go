@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/insomniacslk/neato"
+)
+
+// eventKeepAlive is how often an idle event stream is pinged, so that
+// proxies and clients do not time it out.
+const eventKeepAlive = 30 * time.Second
+
+// eventBuffer is how many events a subscriber may fall behind before new
+// events are dropped for it.
+const eventBuffer = 64
+
+// apiEvent is a robot event as streamed by /events and /events/ws.
+type apiEvent struct {
+	// Type is the snake-cased kind of the watcher event, like
+	// "state_changed", or "run_completed".
+	Type      string            `json:"type"`
+	Time      time.Time         `json:"time"`
+	Serial    string            `json:"serial"`
+	Previous  *neato.RobotState `json:"previous,omitempty"`
+	State     *neato.RobotState `json:"state,omitempty"`
+	Threshold int               `json:"threshold,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	Run       *runInfo          `json:"run,omitempty"`
+}
+
+// runInfo summarizes a completed cleaning run.
+type runInfo struct {
+	// StartedAt is nil if the run started before the daemon.
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	FinishedAt      time.Time  `json:"finished_at"`
+	DurationSeconds float64    `json:"duration_seconds,omitempty"`
+	// Map is the map record of the run, or nil if the cloud has not produced
+	// it yet.
+	Map *neato.Map `json:"map,omitempty"`
+}
+
+const eventTypeRunCompleted = "run_completed"
+
+func newAPIEvent(ev neato.StateEvent) apiEvent {
+	out := apiEvent{
+		Type:      strings.ReplaceAll(ev.Kind.String(), " ", "_"),
+		Time:      ev.Time,
+		Serial:    ev.Serial,
+		Previous:  ev.Previous,
+		State:     ev.Current,
+		Threshold: ev.Threshold,
+	}
+	if ev.Err != nil {
+		out.Error = ev.Err.Error()
+	}
+	return out
+}
+
+// eventHub fans out events to every subscriber.
+type eventHub struct {
+	mu     sync.Mutex
+	subs   map[chan apiEvent]struct{}
+	closed bool
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subs: make(map[chan apiEvent]struct{})}
+}
+
+// subscribe returns a channel of events, closed when the hub is closed.
+func (h *eventHub) subscribe() chan apiEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan apiEvent, eventBuffer)
+	if h.closed {
+		close(ch)
+		return ch
+	}
+	h.subs[ch] = struct{}{}
+	return ch
+}
+
+func (h *eventHub) unsubscribe(ch chan apiEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// publish sends ev to every subscriber, dropping it for those that are too
+// far behind.
+func (h *eventHub) publish(ev apiEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	for ch := range h.subs {
+		select {
+		case ch <- ev:
+		default:
+			if flagDebug {
+				log.Printf("Dropping '%s' event for a slow subscriber", ev.Type)
+			}
+		}
+	}
+}
+
+// close ends every stream, so that they do not hold up a graceful shutdown.
+func (h *eventHub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for ch := range h.subs {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// trackRun publishes a run_completed event when a robot goes back to idle
+// after cleaning. It must be called with d.mu held.
+func (d *daemon) trackRun(ev neato.StateEvent) {
+	switch {
+	case ev.Kind == neato.StateEventInitial && ev.Current.State == neato.StateBusy:
+		d.runs[ev.Serial] = time.Time{}
+	case ev.Kind != neato.StateEventStateChanged:
+	case ev.Current.State == neato.StateBusy && ev.Previous.State != neato.StatePaused:
+		d.runs[ev.Serial] = ev.Time
+	case ev.Current.State == neato.StateIdle && (ev.Previous.State == neato.StateBusy || ev.Previous.State == neato.StatePaused):
+		startedAt, ok := d.runs[ev.Serial]
+		if !ok {
+			return
+		}
+		delete(d.runs, ev.Serial)
+		r, finishedAt := d.bySerial[ev.Serial], ev.Time
+		d.goBackground(func(ctx context.Context) {
+			d.publishRunCompleted(ctx, r, startedAt, finishedAt)
+		})
+	}
+}
+
+func (d *daemon) publishRunCompleted(ctx context.Context, r *neato.Robot, startedAt, finishedAt time.Time) {
+	run := runInfo{FinishedAt: finishedAt}
+	if !startedAt.IsZero() {
+		run.StartedAt = &startedAt
+		run.DurationSeconds = finishedAt.Sub(startedAt).Seconds()
+	}
+	ctx, cancel := context.WithTimeout(ctx, runMapTimeout)
+	defer cancel()
+	maps, err := r.RefreshMaps(ctx)
+	if err != nil {
+		log.Printf("Failed to get the map of the last run of robot '%s': %v", r.Serial, err)
+	} else if len(maps) > 0 {
+		// the newest map is the first one, if it ended with this run (give or
+		// take some clock skew between us and the cloud)
+		if end, err := maps[0].EndTime(); err == nil && end.After(finishedAt.Add(-5*time.Minute)) {
+			run.Map = maps[0]
+		}
+	}
+	d.events.publish(apiEvent{Type: eventTypeRunCompleted, Time: finishedAt, Serial: r.Serial, Run: &run})
+}
+
+// snapshot returns an initial event for every robot with a known state, sent
+// to new subscribers before the live events.
+func (d *daemon) snapshot(serial string) []apiEvent {
+	var events []apiEvent
+	for _, r := range d.robots {
+		if serial != "" && r.Serial != serial {
+			continue
+		}
+		st, ok := d.lastStatus(r.Serial)
+		if !ok || st.State == nil {
+			continue
+		}
+		events = append(events, newAPIEvent(neato.StateEvent{
+			Kind:    neato.StateEventInitial,
+			Time:    st.Updated,
+			Serial:  r.Serial,
+			Current: st.State,
+		}))
+	}
+	return events
+}
+
+// eventFilter returns the robot serial requested with ?serial=, and whether
+// it is valid.
+func (d *daemon) eventFilter(w http.ResponseWriter, req *http.Request) (string, bool) {
+	serial := req.URL.Query().Get("serial")
+	if serial == "" {
+		return "", true
+	}
+	if _, ok := d.bySerial[serial]; !ok {
+		writeAPIError(w, http.StatusNotFound, fmt.Errorf("robot with serial '%s' not found", serial))
+		return "", false
+	}
+	return serial, true
+}
+
+// handleEvents streams events as Server-Sent Events, with the event type as
+// the SSE event name and the JSON event as data.
+func (d *daemon) handleEvents(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+	serial, ok := d.eventFilter(w, req)
+	if !ok {
+		return
+	}
+	ch := d.events.subscribe()
+	defer d.events.unsubscribe(ch)
+
+	if origin := req.Header.Get("Origin"); origin != "" {
+		// checkRequest only lets allowed origins through
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Vary", "Origin")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	write := func(ev apiEvent) error {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	for _, ev := range d.snapshot(serial) {
+		if err := write(ev); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(eventKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if serial != "" && ev.Serial != serial {
+				continue
+			}
+			if err := write(ev); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// handleEventsWebSocket streams events as JSON text messages over a
+// WebSocket.
+func (d *daemon) handleEventsWebSocket(w http.ResponseWriter, req *http.Request) {
+	serial, ok := d.eventFilter(w, req)
+	if !ok {
+		return
+	}
+	conn, err := upgradeWebSocket(w, req, d.originAllowed)
+	if err != nil {
+		if flagDebug {
+			log.Printf("WebSocket upgrade failed: %v", err)
+		}
+		return
+	}
+	defer conn.Close()
+	ch := d.events.subscribe()
+	defer d.events.unsubscribe(ch)
+
+	clientGone := make(chan struct{})
+	go conn.readLoop(clientGone)
+
+	write := func(ev apiEvent) error {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return err
+		}
+		return conn.writeFrame(wsOpText, data)
+	}
+	for _, ev := range d.snapshot(serial) {
+		if err := write(ev); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(eventKeepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-clientGone:
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				// 1001: going away
+				_ = conn.writeClose(1001)
+				return
+			}
+			if serial != "" && ev.Serial != serial {
+				continue
+			}
+			if err := write(ev); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.writeFrame(wsOpPing, nil); err != nil {
+				return
+			}
+		}
+	}
+}
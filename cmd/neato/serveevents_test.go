@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/neato"
+)
+
+// newEventsTestDaemon returns a daemon for two idle robots, "serial-1" and
+// "serial-2", served over HTTP.
+func newEventsTestDaemon(t *testing.T) (*daemon, *httptest.Server) {
+	d := newDaemon([]*neato.Robot{{Serial: "serial-1"}, {Serial: "serial-2"}}, nil, newAPIMetrics())
+	for _, serial := range []string{"serial-1", "serial-2"} {
+		d.handleEvent(neato.StateEvent{
+			Kind:    neato.StateEventInitial,
+			Time:    time.Now(),
+			Serial:  serial,
+			Current: &neato.RobotState{State: neato.StateIdle},
+		})
+	}
+	srv := httptest.NewServer(d.handler())
+	// shutdown ends the streams, which the server waits for when closing
+	t.Cleanup(func() {
+		d.shutdown()
+		srv.Close()
+	})
+	return d, srv
+}
+
+func stateChanged(serial string, from, to neato.State, at time.Time) neato.StateEvent {
+	return neato.StateEvent{
+		Kind:     neato.StateEventStateChanged,
+		Time:     at,
+		Serial:   serial,
+		Previous: &neato.RobotState{State: from},
+		Current:  &neato.RobotState{State: to},
+	}
+}
+
+// readSSE reads the next Server-Sent Event, skipping keep-alives.
+func readSSE(t *testing.T, r *bufio.Reader) (string, apiEvent) {
+	t.Helper()
+	var (
+		name string
+		ev   apiEvent
+	)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return name, ev
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev); err != nil {
+				t.Fatalf("invalid event data %q: %v", line, err)
+			}
+		}
+	}
+}
+
+func TestEventsSSE(t *testing.T) {
+	d, srv := newEventsTestDaemon(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events?serial=serial-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("got Content-Type '%s', want text/event-stream", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+
+	// the snapshot only covers the requested robot
+	if name, ev := readSSE(t, r); name != "initial" || ev.Serial != "serial-1" || ev.State == nil || ev.State.State != neato.StateIdle {
+		t.Fatalf("got snapshot event '%s' %+v, want the initial state of serial-1", name, ev)
+	}
+	d.handleEvent(stateChanged("serial-2", neato.StateIdle, neato.StateError, time.Now()))
+	d.handleEvent(stateChanged("serial-1", neato.StateIdle, neato.StatePaused, time.Now()))
+	if name, ev := readSSE(t, r); name != "state_changed" || ev.Serial != "serial-1" || ev.State.State != neato.StatePaused {
+		t.Fatalf("got event '%s' %+v, want serial-1 to be paused", name, ev)
+	}
+}
+
+func TestEventsUnknownSerial(t *testing.T) {
+	_, srv := newEventsTestDaemon(t)
+	for _, path := range []string{"/events?serial=serial-3", "/events/ws?serial=serial-3"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("got status %d for %s, want %d", resp.StatusCode, path, http.StatusNotFound)
+		}
+	}
+}
+
+// webSocketHandshake opens /events/ws with the given Origin header, if any,
+// and returns the HTTP status of the handshake.
+func webSocketHandshake(t *testing.T, srv *httptest.Server, origin string) int {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	req := "GET /events/ws HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"
+	if origin != "" {
+		req += "Origin: " + origin + "\r\n"
+	}
+	if _, err := fmt.Fprint(conn, req+"\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestEventsWebSocketOrigin(t *testing.T) {
+	d, srv := newEventsTestDaemon(t)
+	d.allowedOrigins = []string{"http://localhost:3000"}
+	for _, tc := range []struct {
+		origin     string
+		wantStatus int
+	}{
+		{"", http.StatusSwitchingProtocols},
+		{"http://localhost:3000", http.StatusSwitchingProtocols},
+		{"https://example.com", http.StatusForbidden},
+	} {
+		if got := webSocketHandshake(t, srv, tc.origin); got != tc.wantStatus {
+			t.Errorf("got status %d for origin '%s', want %d", got, tc.origin, tc.wantStatus)
+		}
+	}
+}
+
+func TestUpgradeWebSocketOrigin(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/events/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Origin", "https://example.com")
+	w := httptest.NewRecorder()
+	if _, err := upgradeWebSocket(w, req, func(*http.Request) bool { return false }); err == nil || w.Code != http.StatusForbidden {
+		t.Errorf("got error %v and status %d, want the origin to be rejected", err, w.Code)
+	}
+}
+
+// newTestCloudRobot returns a robot backed by a fake Beehive server, whose
+// only map ended at endAt. Nothing is cached, so that every lookup reaches
+// the server.
+func newTestCloudRobot(t *testing.T, endAt time.Time) *neato.Robot {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/me/robots", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprint(w, `[{"serial":"serial-1","name":"robot"}]`)
+	})
+	mux.HandleFunc("/users/me/robots/serial-1/maps", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, `{"maps":[{"id":"map-1","end_at":%q}]}`, endAt.UTC().Format(time.RFC3339))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	header := url.Values{}
+	header.Set("Authorization", "Token token=test")
+	robots, err := neato.NewAccountWithCache(neato.NewPasswordSession(srv.URL, &header), nil).Robots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return robots[0]
+}
+
+func TestRunCompleted(t *testing.T) {
+	finishedAt := time.Now()
+	startedAt := finishedAt.Add(-10 * time.Minute)
+	for _, tc := range []struct {
+		name        string
+		events      []neato.StateEvent
+		wantStarted bool
+	}{
+		{
+			name: "run started before the daemon",
+			events: []neato.StateEvent{
+				{Kind: neato.StateEventInitial, Time: startedAt, Serial: "serial-1", Current: &neato.RobotState{State: neato.StateBusy}},
+				stateChanged("serial-1", neato.StateBusy, neato.StateIdle, finishedAt),
+			},
+		},
+		{
+			name: "paused run",
+			events: []neato.StateEvent{
+				{Kind: neato.StateEventInitial, Time: startedAt, Serial: "serial-1", Current: &neato.RobotState{State: neato.StateIdle}},
+				stateChanged("serial-1", neato.StateIdle, neato.StateBusy, startedAt),
+				stateChanged("serial-1", neato.StateBusy, neato.StatePaused, startedAt.Add(time.Minute)),
+				stateChanged("serial-1", neato.StatePaused, neato.StateBusy, startedAt.Add(2*time.Minute)),
+				stateChanged("serial-1", neato.StateBusy, neato.StateIdle, finishedAt),
+			},
+			wantStarted: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newTestCloudRobot(t, finishedAt)
+			d := newDaemon([]*neato.Robot{r}, nil, newAPIMetrics())
+			t.Cleanup(d.shutdown)
+			ch := d.events.subscribe()
+			for _, ev := range tc.events {
+				d.handleEvent(ev)
+			}
+
+			timeout := time.After(5 * time.Second)
+			for {
+				var ev apiEvent
+				select {
+				case ev = <-ch:
+				case <-timeout:
+					t.Fatal("no run_completed event")
+				}
+				if ev.Type != eventTypeRunCompleted {
+					continue
+				}
+				run := ev.Run
+				if ev.Serial != "serial-1" || run == nil || !run.FinishedAt.Equal(finishedAt) || run.Map == nil || run.Map.ID != "map-1" {
+					t.Fatalf("unexpected run_completed event %+v", ev)
+				}
+				if started := run.StartedAt != nil; started != tc.wantStarted {
+					t.Errorf("got start time %v, want one: %v", run.StartedAt, tc.wantStarted)
+				}
+				if tc.wantStarted && (!run.StartedAt.Equal(startedAt) || run.DurationSeconds != 600) {
+					t.Errorf("got run from %v for %vs, want from %v for 600s", run.StartedAt, run.DurationSeconds, startedAt)
+				}
+				return
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/insomniacslk/neato"
+)
+
+// apiLatencyBuckets are the upper bounds, in seconds, of the API latency
+// histogram buckets.
+var apiLatencyBuckets = []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type histogram struct {
+	// counts[i] is the number of observations in bucket i, not cumulative
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// apiMetrics collects the latency and errors of the API calls, fed by
+// neato.APICallHook.
+type apiMetrics struct {
+	mu        sync.Mutex
+	latencies map[string]*histogram
+	// errors is keyed by endpoint and error kind
+	errors map[[2]string]uint64
+}
+
+func newAPIMetrics() *apiMetrics {
+	return &apiMetrics{
+		latencies: make(map[string]*histogram),
+		errors:    make(map[[2]string]uint64),
+	}
+}
+
+// apiErrorKind classifies a failed API call for the errors metric.
+func apiErrorKind(call neato.APICall) string {
+	var result *neato.ResultError
+	switch {
+	case call.FastFail:
+		return "fast_fail"
+	case errors.Is(call.Err, neato.ErrRobotOffline):
+		return "offline"
+	case errors.As(call.Err, &result):
+		return "result"
+	default:
+		return "other"
+	}
+}
+
+func (m *apiMetrics) observe(call neato.APICall) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if call.Err != nil {
+		m.errors[[2]string{call.Endpoint, apiErrorKind(call)}]++
+	}
+	if call.FastFail {
+		// nothing was sent, so there is no latency to observe
+		return
+	}
+	h, ok := m.latencies[call.Endpoint]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(apiLatencyBuckets))}
+		m.latencies[call.Endpoint] = h
+	}
+	seconds := call.Duration.Seconds()
+	for i, le := range apiLatencyBuckets {
+		if seconds <= le {
+			h.counts[i]++
+			break
+		}
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// metricsWriter writes metrics in the Prometheus text exposition format.
+type metricsWriter struct {
+	w io.Writer
+}
+
+func (mw *metricsWriter) header(name, typ, help string) {
+	fmt.Fprintf(mw.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes a sample. labels is a list of alternating names and values.
+func (mw *metricsWriter) sample(name string, value float64, labels ...string) {
+	fmt.Fprint(mw.w, name)
+	if len(labels) > 0 {
+		pairs := make([]string, 0, len(labels)/2)
+		for i := 0; i+1 < len(labels); i += 2 {
+			pairs = append(pairs, labels[i]+`="`+escapeLabelValue(labels[i+1])+`"`)
+		}
+		fmt.Fprintf(mw.w, "{%s}", strings.Join(pairs, ","))
+	}
+	fmt.Fprintf(mw.w, " %s\n", strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func escapeLabelValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (m *apiMetrics) write(mw *metricsWriter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	endpoints := make([]string, 0, len(m.latencies))
+	for endpoint := range m.latencies {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	mw.header("neato_api_request_duration_seconds", "histogram", "Latency of the requests to the Neato cloud and to the robots, except for those failed fast by the circuit breaker of a robot.")
+	for _, endpoint := range endpoints {
+		h := m.latencies[endpoint]
+		var cumulative uint64
+		for i, le := range apiLatencyBuckets {
+			cumulative += h.counts[i]
+			mw.sample("neato_api_request_duration_seconds_bucket", float64(cumulative), "endpoint", endpoint, "le", strconv.FormatFloat(le, 'g', -1, 64))
+		}
+		mw.sample("neato_api_request_duration_seconds_bucket", float64(h.count), "endpoint", endpoint, "le", "+Inf")
+		mw.sample("neato_api_request_duration_seconds_sum", h.sum, "endpoint", endpoint)
+		mw.sample("neato_api_request_duration_seconds_count", float64(h.count), "endpoint", endpoint)
+	}
+
+	keys := make([][2]string, 0, len(m.errors))
+	for key := range m.errors {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	mw.header("neato_api_errors_total", "counter", "Failed requests to the Neato cloud and to the robots, by kind of error (fast_fail, offline, result, other).")
+	for _, key := range keys {
+		mw.sample("neato_api_errors_total", float64(m.errors[key]), "endpoint", key[0], "kind", key[1])
+	}
+}
+
+// robotGauge is a per-robot gauge computed from the last polled state.
+type robotGauge struct {
+	name  string
+	help  string
+	value func(*neato.RobotState) float64
+}
+
+var robotGauges = []robotGauge{
+	{"neato_robot_charge_percent", "Battery charge of the robot.", func(s *neato.RobotState) float64 { return float64(s.Details.Charge) }},
+	{"neato_robot_state", "State of the robot: 0 invalid, 1 idle, 2 busy, 3 paused, 4 error.", func(s *neato.RobotState) float64 { return float64(s.State) }},
+	{"neato_robot_action", "Action of the robot, as the numeric Nucleo action code.", func(s *neato.RobotState) float64 { return float64(s.Action) }},
+	{"neato_robot_docked", "Whether the robot is on its base.", func(s *neato.RobotState) float64 { return boolValue(s.Details.IsDocked) }},
+	{"neato_robot_charging", "Whether the robot is charging.", func(s *neato.RobotState) float64 { return boolValue(s.Details.IsCharging) }},
+	{"neato_robot_schedule_enabled", "Whether the cleaning schedule of the robot is enabled.", func(s *neato.RobotState) float64 { return boolValue(s.Details.IsScheduleEnabled) }},
+}
+
+// historyGauge is a per-robot gauge computed from the map history. The cloud
+// only keeps the most recent runs, so these values drop when old runs leave
+// the history, and are not counters.
+type historyGauge struct {
+	name  string
+	help  string
+	value func(*neato.MapStats) float64
+}
+
+var historyGauges = []historyGauge{
+	{"neato_robot_history_runs", "Cleaning runs in the map history of the robot.", func(s *neato.MapStats) float64 { return float64(s.Runs) }},
+	{"neato_robot_history_cleaned_area_square_meters", "Area cleaned in the map history of the robot.", func(s *neato.MapStats) float64 { return s.CleanedArea }},
+	{"neato_robot_history_error_seconds", "Time spent in error in the map history of the robot.", func(s *neato.MapStats) float64 { return s.ErrorTime.Seconds() }},
+	{"neato_robot_history_pause_seconds", "Time spent paused in the map history of the robot.", func(s *neato.MapStats) float64 { return s.PauseTime.Seconds() }},
+}
+
+// handleMetrics serves the metrics in the Prometheus text format. Robot
+// gauges come from the last polled state and from the map history, which is
+// cached by the account.
+func (d *daemon) handleMetrics(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method))
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	mw := metricsWriter{w: w}
+
+	mw.header("neato_robot_online", "gauge", "Whether the last poll of the robot succeeded.")
+	for _, r := range d.robots {
+		st, ok := d.lastStatus(r.Serial)
+		mw.sample("neato_robot_online", boolValue(ok && st.Err == nil), "serial", r.Serial, "name", r.Name)
+	}
+	for _, g := range robotGauges {
+		mw.header(g.name, "gauge", g.help)
+		for _, r := range d.robots {
+			if st, ok := d.lastStatus(r.Serial); ok && st.State != nil {
+				mw.sample(g.name, g.value(st.State), "serial", r.Serial, "name", r.Name)
+			}
+		}
+	}
+
+	stats := make(map[string]*neato.MapStats)
+	for _, r := range d.robots {
+		_, s, err := r.MapHistoryContext(req.Context())
+		if err != nil {
+			if req.Context().Err() != nil {
+				// the scraper gave up
+				return
+			}
+			log.Printf("Failed to get the map history of robot '%s': %v", r.Serial, err)
+			continue
+		}
+		stats[r.Serial] = s
+	}
+	for _, g := range historyGauges {
+		mw.header(g.name, "gauge", g.help)
+		for _, r := range d.robots {
+			if s, ok := stats[r.Serial]; ok {
+				mw.sample(g.name, g.value(s), "serial", r.Serial, "name", r.Name)
+			}
+		}
+	}
+
+	d.metrics.write(&mw)
+}
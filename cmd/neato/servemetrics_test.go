@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/neato"
+)
+
+func TestAPIMetricsWrite(t *testing.T) {
+	m := newAPIMetrics()
+	offline := fmt.Errorf("robot 'serial-1': %w", neato.ErrRobotOffline)
+	for _, call := range []neato.APICall{
+		{Endpoint: "nucleo getRobotState", Duration: 50 * time.Millisecond},
+		// on a bucket bound, which is inclusive
+		{Endpoint: "nucleo getRobotState", Duration: 250 * time.Millisecond},
+		{Endpoint: "nucleo getRobotState", Duration: 3 * time.Second, Err: offline},
+		// above every bucket
+		{Endpoint: "nucleo getRobotState", Duration: 20 * time.Second},
+		// not sent, so only counted as an error
+		{Endpoint: "nucleo getRobotState", Duration: time.Microsecond, Err: offline, FastFail: true},
+		{Endpoint: "GET users/me/robots", Duration: time.Second, Err: errors.New("connection refused")},
+		{Endpoint: "nucleo startCleaning", Duration: time.Second, Err: &neato.ResultError{Cmd: "startCleaning", Result: "not_on_charge_base"}},
+	} {
+		m.observe(call)
+	}
+	var sb strings.Builder
+	m.write(&metricsWriter{w: &sb})
+
+	want := `# HELP neato_api_request_duration_seconds Latency of the requests to the Neato cloud and to the robots, except for those failed fast by the circuit breaker of a robot.
+# TYPE neato_api_request_duration_seconds histogram
+neato_api_request_duration_seconds_bucket{endpoint="GET users/me/robots",le="0.1"} 0
+neato_api_request_duration_seconds_bucket{endpoint="GET users/me/robots",le="0.25"} 0
+neato_api_request_duration_seconds_bucket{endpoint="GET users/me/robots",le="0.5"} 0
+neato_api_request_duration_seconds_bucket{endpoint="GET users/me/robots",le="1"} 1
+neato_api_request_duration_seconds_bucket{endpoint="GET users/me/robots",le="2.5"} 1
+neato_api_request_duration_seconds_bucket{endpoint="GET users/me/robots",le="5"} 1
+neato_api_request_duration_seconds_bucket{endpoint="GET users/me/robots",le="10"} 1
+neato_api_request_duration_seconds_bucket{endpoint="GET users/me/robots",le="+Inf"} 1
+neato_api_request_duration_seconds_sum{endpoint="GET users/me/robots"} 1
+neato_api_request_duration_seconds_count{endpoint="GET users/me/robots"} 1
+neato_api_request_duration_seconds_bucket{endpoint="nucleo getRobotState",le="0.1"} 1
+neato_api_request_duration_seconds_bucket{endpoint="nucleo getRobotState",le="0.25"} 2
+neato_api_request_duration_seconds_bucket{endpoint="nucleo getRobotState",le="0.5"} 2
+neato_api_request_duration_seconds_bucket{endpoint="nucleo getRobotState",le="1"} 2
+neato_api_request_duration_seconds_bucket{endpoint="nucleo getRobotState",le="2.5"} 2
+neato_api_request_duration_seconds_bucket{endpoint="nucleo getRobotState",le="5"} 3
+neato_api_request_duration_seconds_bucket{endpoint="nucleo getRobotState",le="10"} 3
+neato_api_request_duration_seconds_bucket{endpoint="nucleo getRobotState",le="+Inf"} 4
+neato_api_request_duration_seconds_sum{endpoint="nucleo getRobotState"} 23.3
+neato_api_request_duration_seconds_count{endpoint="nucleo getRobotState"} 4
+neato_api_request_duration_seconds_bucket{endpoint="nucleo startCleaning",le="0.1"} 0
+neato_api_request_duration_seconds_bucket{endpoint="nucleo startCleaning",le="0.25"} 0
+neato_api_request_duration_seconds_bucket{endpoint="nucleo startCleaning",le="0.5"} 0
+neato_api_request_duration_seconds_bucket{endpoint="nucleo startCleaning",le="1"} 1
+neato_api_request_duration_seconds_bucket{endpoint="nucleo startCleaning",le="2.5"} 1
+neato_api_request_duration_seconds_bucket{endpoint="nucleo startCleaning",le="5"} 1
+neato_api_request_duration_seconds_bucket{endpoint="nucleo startCleaning",le="10"} 1
+neato_api_request_duration_seconds_bucket{endpoint="nucleo startCleaning",le="+Inf"} 1
+neato_api_request_duration_seconds_sum{endpoint="nucleo startCleaning"} 1
+neato_api_request_duration_seconds_count{endpoint="nucleo startCleaning"} 1
+# HELP neato_api_errors_total Failed requests to the Neato cloud and to the robots, by kind of error (fast_fail, offline, result, other).
+# TYPE neato_api_errors_total counter
+neato_api_errors_total{endpoint="GET users/me/robots",kind="other"} 1
+neato_api_errors_total{endpoint="nucleo getRobotState",kind="fast_fail"} 1
+neato_api_errors_total{endpoint="nucleo getRobotState",kind="offline"} 1
+neato_api_errors_total{endpoint="nucleo startCleaning",kind="result"} 1
+`
+	if got := sb.String(); got != want {
+		t.Errorf("got metrics:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMetricsSampleEscaping(t *testing.T) {
+	var sb strings.Builder
+	(&metricsWriter{w: &sb}).sample("neato_robot_online", 1, "serial", "serial-1", "name", "Robot \"R2\"\\kitchen\n")
+	if got, want := sb.String(), `neato_robot_online{serial="serial-1",name="Robot \"R2\"\\kitchen\n"} 1`+"\n"; got != want {
+		t.Errorf("got sample %q, want %q", got, want)
+	}
+}
+
+func TestHandleMetrics(t *testing.T) {
+	r := newTestCloudRobot(t, time.Now())
+	d := newDaemon([]*neato.Robot{r}, nil, newAPIMetrics())
+	t.Cleanup(d.shutdown)
+	state := neato.RobotState{State: neato.StateIdle}
+	state.Details.Charge = 80
+	state.Details.IsDocked = true
+	d.handleEvent(neato.StateEvent{Kind: neato.StateEventInitial, Time: time.Now(), Serial: r.Serial, Current: &state})
+
+	w := httptest.NewRecorder()
+	d.handleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("got Content-Type '%s', want the Prometheus text format", ct)
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		`# TYPE neato_robot_online gauge`,
+		`neato_robot_online{serial="serial-1",name="robot"} 1`,
+		`neato_robot_charge_percent{serial="serial-1",name="robot"} 80`,
+		`neato_robot_docked{serial="serial-1",name="robot"} 1`,
+		`# TYPE neato_robot_history_runs gauge`,
+		`neato_robot_history_runs{serial="serial-1",name="robot"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing line '%s' in metrics:\n%s", line, body)
+		}
+	}
+
+	// the map history is looked up with the context of the request
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w = httptest.NewRecorder()
+	d.handleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil).WithContext(ctx))
+	if body := w.Body.String(); strings.Contains(body, "neato_robot_history_runs{") {
+		t.Errorf("got history metrics for a canceled request:\n%s", body)
+	}
+}
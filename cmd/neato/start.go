@@ -1,12 +1,21 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"strconv"
+	"time"
+
+	"github.com/insomniacslk/neato"
 
 	"github.com/spf13/cobra"
 )
 
+var flagStartWait bool
+
 var startCmd = &cobra.Command{
 	Use:   "start",
 	Short: "Start cleaning",
@@ -36,11 +45,34 @@ var startCmd = &cobra.Command{
 			log.Fatalf("Robot index is too high: got %d, must be in range 0-%d", robotIdx, len(robots)-1)
 		}
 		robot := robots[robotIdx]
-		if err := robot.Start(nil); err != nil {
-			log.Fatalf("Failed to start robot: %v", err)
+		if !flagStartWait {
+			if err := robot.Start(context.Background(), nil); err != nil {
+				log.Fatalf("Failed to start robot: %v", err)
+			}
+			return
+		}
+		summary, err := robot.StartAndWait(context.Background(), nil, nil, func(p neato.Progress) {
+			log.Printf("Robot '%s': %s (elapsed: %s)", robot.Name, p.Phase, p.Elapsed.Round(time.Second))
+		})
+		if errors.Is(err, neato.ErrRunMapUnavailable) {
+			log.Printf("Warning: %v", err)
+		} else if err != nil {
+			log.Fatalf("Cleaning failed: %v", err)
+		} else if summary.Map == nil {
+			log.Printf("Warning: no map found for this run")
+		}
+		if flagJSON {
+			j, err := json.Marshal(summary)
+			if err != nil {
+				log.Fatalf("Failed to marshal to JSON: %v", err)
+			}
+			fmt.Println(string(j))
+		} else {
+			fmt.Printf("%s\n", summary)
 		}
 	},
 }
 
 func initStartCmd() {
+	startCmd.Flags().BoolVarP(&flagStartWait, "wait", "w", false, "Wait for the robot to finish cleaning and return to base")
 }
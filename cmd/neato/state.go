@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"strconv"
 
+	"github.com/insomniacslk/neato"
 	"github.com/spf13/cobra"
 )
 
+var flagStateAll bool
+
 var stateCmd = &cobra.Command{
 	Use:   "state",
 	Short: "Get robot state",
@@ -27,6 +31,10 @@ var stateCmd = &cobra.Command{
 		if err != nil {
 			log.Fatalf("Account lookup failed: %v", err)
 		}
+		if flagStateAll {
+			printAllStates(acc)
+			return
+		}
 		robots, err := acc.Robots()
 		if err != nil {
 			log.Fatalf("Cannot get robots: %v", err)
@@ -38,7 +46,7 @@ var stateCmd = &cobra.Command{
 			log.Fatalf("Robot index is too high: got %d, must be in range 0-%d", robotIdx, len(robots)-1)
 		}
 		robot := robots[robotIdx]
-		state, err := robot.State()
+		state, err := robot.State(context.Background())
 		if err != nil {
 			log.Fatalf("Failed to get robot state: %v", err)
 		}
@@ -50,9 +58,63 @@ var stateCmd = &cobra.Command{
 			fmt.Println(string(j))
 		} else {
 			fmt.Printf("%s\n", state)
+			if state.Error != nil && *state.Error != "" {
+				printCodeInfo("Error", string(*state.Error), state.Error.Info())
+			}
+			if state.Alert != nil && state.Alert.IsSet() {
+				printCodeInfo("Alert", string(*state.Alert), state.Alert.Info())
+			}
 		}
 	},
 }
 
+func printCodeInfo(kind, code string, info neato.CodeInfo) {
+	fmt.Printf("%s [%s]: %s (%s)\n", kind, info.Severity, info.Description, code)
+	if info.Remediation != "" {
+		fmt.Printf("  Suggested fix: %s\n", info.Remediation)
+	}
+}
+
+// printAllStates fetches the state of every robot concurrently.
+func printAllStates(acc *neato.Account) {
+	robots, err := acc.Robots()
+	if err != nil {
+		log.Fatalf("Cannot get robots: %v", err)
+	}
+	results, err := acc.States(context.Background(), flagConcurrency)
+	if err != nil {
+		log.Fatalf("Cannot get robot states: %v", err)
+	}
+	if flagJSON {
+		type stateOrError struct {
+			State *neato.RobotState `json:"state,omitempty"`
+			Error string            `json:"error,omitempty"`
+		}
+		out := make(map[string]stateOrError)
+		for serial, res := range results {
+			entry := stateOrError{State: res.State}
+			if res.Err != nil {
+				entry.Error = res.Err.Error()
+			}
+			out[serial] = entry
+		}
+		j, err := json.Marshal(out)
+		if err != nil {
+			log.Fatalf("Failed to marshal to JSON: %v", err)
+		}
+		fmt.Println(string(j))
+		return
+	}
+	for _, r := range robots {
+		res := results[r.Serial]
+		if res.Err != nil {
+			fmt.Printf("Robot '%s' (serial: '%s'): failed to get state: %v\n", r.Name, r.Serial, res.Err)
+			continue
+		}
+		fmt.Printf("Robot '%s' (serial: '%s'): %s\n", r.Name, r.Serial, res.State)
+	}
+}
+
 func initStateCmd() {
+	stateCmd.Flags().BoolVarP(&flagStateAll, "all", "a", false, "Show the state of every robot")
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize the cleaning history of every robot",
+	Run: func(cmd *cobra.Command, args []string) {
+		acc, err := getAccount()
+		if err != nil {
+			log.Fatalf("Account lookup failed: %v", err)
+		}
+		robots, err := acc.Robots()
+		if err != nil {
+			log.Fatalf("Cannot get robots: %v", err)
+		}
+		if len(robots) == 0 {
+			fmt.Println("No robots found")
+			return
+		}
+		results, err := acc.MapsByRobot(context.Background(), flagConcurrency)
+		if err != nil {
+			log.Fatalf("Cannot get maps: %v", err)
+		}
+		for _, r := range robots {
+			stats, err := results[r.Serial].Stats, results[r.Serial].Err
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to get map history for robot '%s' (serial: '%s'): %v\n", r.Name, r.Serial, err)
+				continue
+			}
+			if flagJSON {
+				j, err := json.Marshal(stats)
+				if err != nil {
+					log.Fatalf("Failed to marshal to JSON: %v", err)
+				}
+				fmt.Println(string(j))
+			} else {
+				fmt.Printf("Robot '%s' (serial: '%s')\n", r.Name, r.Serial)
+				fmt.Printf("  Runs:                %d\n", stats.Runs)
+				if stats.Runs > 0 {
+					fmt.Printf("  First run:           %s\n", stats.FirstRun.Format(time.RFC1123))
+					fmt.Printf("  Last run:            %s\n", stats.LastRun.Format(time.RFC1123))
+				}
+				fmt.Printf("  Cleaned area:        %.2f sqm (%.2f sqm per run)\n", stats.CleanedArea, stats.AverageCleanedArea())
+				fmt.Printf("  Cleaning time:       %s\n", stats.CleaningTime)
+				fmt.Printf("  Time in pause:       %s\n", stats.PauseTime)
+				fmt.Printf("  Time in error:       %s\n", stats.ErrorTime)
+				fmt.Printf("  Suspended cleaning:  %s (%d recharges)\n", stats.SuspendedCleaningTime, stats.RechargeCount)
+				if len(stats.Server) > 0 {
+					fmt.Println("  Reported by the server:")
+					keys := make([]string, 0, len(stats.Server))
+					for k := range stats.Server {
+						keys = append(keys, k)
+					}
+					sort.Strings(keys)
+					for _, k := range keys {
+						fmt.Printf("    %s: %g\n", k, stats.Server[k])
+					}
+				}
+			}
+		}
+	},
+}
+
+func initStatsCmd() {
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"strconv"
 
@@ -36,7 +37,7 @@ var stopCmd = &cobra.Command{
 			log.Fatalf("Robot index is too high: got %d, must be in range 0-%d", robotIdx, len(robots)-1)
 		}
 		robot := robots[robotIdx]
-		if err := robot.Stop(); err != nil {
+		if err := robot.Stop(context.Background()); err != nil {
 			log.Fatalf("Failed to stop robot: %v", err)
 		}
 	},
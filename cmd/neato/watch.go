@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/insomniacslk/neato"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagWatchInterval time.Duration
+	flagWatchLogLines int
+)
+
+// highlightFor is how long a changed field stays highlighted.
+const highlightFor = 10 * time.Second
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Show a live dashboard of every robot",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := checkWatchFlags(flagWatchInterval, flagWatchLogLines); err != nil {
+			log.Fatal(err)
+		}
+		acc, err := getAccount()
+		if err != nil {
+			log.Fatalf("Account lookup failed: %v", err)
+		}
+		robots, err := acc.Robots()
+		if err != nil {
+			log.Fatalf("Cannot get robots: %v", err)
+		}
+		if len(robots) == 0 {
+			fmt.Println("No robots found")
+			return
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		d := newDashboard(robots, flagWatchLogLines)
+		var wg sync.WaitGroup
+		for _, r := range robots {
+			events := r.Watch(ctx, flagWatchInterval)
+			wg.Add(1)
+			go func(r *neato.Robot, events <-chan neato.StateEvent) {
+				defer wg.Done()
+				d.updateLastRun(ctx, r)
+				for ev := range events {
+					d.handle(r, ev)
+					if ev.Kind == neato.StateEventDocked {
+						wg.Add(1)
+						go func() {
+							defer wg.Done()
+							d.updateLastRun(ctx, r)
+						}()
+					}
+				}
+			}(r, events)
+		}
+
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			d.render(os.Stdout)
+			select {
+			case <-ctx.Done():
+				wg.Wait()
+				fmt.Println()
+				return
+			case <-ticker.C:
+			}
+		}
+	},
+}
+
+type robotRow struct {
+	robot     *neato.Robot
+	state     *neato.RobotState
+	err       error
+	lastRun   string
+	changedAt map[string]time.Time
+}
+
+type dashboard struct {
+	mu       sync.Mutex
+	rows     []*robotRow
+	bySerial map[string]*robotRow
+	events   []string
+	logLines int
+}
+
+// checkWatchFlags validates the flags of the watch command.
+func checkWatchFlags(interval time.Duration, logLines int) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid --interval %s, must be positive", interval)
+	}
+	if logLines < 0 {
+		return fmt.Errorf("invalid --log-lines %d, must not be negative", logLines)
+	}
+	return nil
+}
+
+func newDashboard(robots []*neato.Robot, logLines int) *dashboard {
+	d := dashboard{bySerial: make(map[string]*robotRow), logLines: logLines}
+	for _, r := range robots {
+		row := robotRow{robot: r, lastRun: "-", changedAt: make(map[string]time.Time)}
+		d.rows = append(d.rows, &row)
+		d.bySerial[r.Serial] = &row
+	}
+	return &d
+}
+
+func (d *dashboard) updateLastRun(ctx context.Context, r *neato.Robot) {
+	maps, err := r.RefreshMaps(ctx)
+	lastRun := "-"
+	if err == nil && len(maps) > 0 && maps[0].CleanedArea != nil {
+		lastRun = fmt.Sprintf("%.1f sqm", *maps[0].CleanedArea)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	row := d.bySerial[r.Serial]
+	if row.lastRun != lastRun {
+		row.lastRun = lastRun
+		row.changedAt["lastRun"] = time.Now()
+	}
+}
+
+func (d *dashboard) handle(r *neato.Robot, ev neato.StateEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	row := d.bySerial[r.Serial]
+	now := time.Now()
+	switch ev.Kind {
+	case neato.StateEventPollError:
+		row.err = ev.Err
+		row.changedAt["online"] = now
+	case neato.StateEventInitial:
+		row.err = nil
+		row.state = ev.Current
+	default:
+		if row.err != nil {
+			row.err = nil
+			row.changedAt["online"] = now
+		}
+		row.state = ev.Current
+		switch ev.Kind {
+		case neato.StateEventStateChanged:
+			row.changedAt["state"] = now
+		case neato.StateEventActionChanged:
+			row.changedAt["action"] = now
+		case neato.StateEventChargeThreshold:
+			row.changedAt["charge"] = now
+		case neato.StateEventDocked, neato.StateEventUndocked:
+			row.changedAt["docked"] = now
+		case neato.StateEventAlertRaised, neato.StateEventAlertCleared:
+			row.changedAt["alert"] = now
+		case neato.StateEventErrorRaised, neato.StateEventErrorCleared:
+			row.changedAt["error"] = now
+		}
+	}
+	if ev.Kind != neato.StateEventInitial && ev.Kind != neato.StateEventPolled {
+		d.events = append(d.events, fmt.Sprintf("%s  %-12s %s", ev.Time.Format("15:04:05"), r.Name, describeEvent(ev)))
+		if len(d.events) > d.logLines {
+			d.events = d.events[len(d.events)-d.logLines:]
+		}
+	}
+}
+
+func describeEvent(ev neato.StateEvent) string {
+	switch ev.Kind {
+	case neato.StateEventPollError:
+		return fmt.Sprintf("offline: %v", ev.Err)
+	case neato.StateEventStateChanged:
+		return fmt.Sprintf("state %s -> %s", ev.Previous.State, ev.Current.State)
+	case neato.StateEventActionChanged:
+		return fmt.Sprintf("action %s -> %s", ev.Previous.Action, ev.Current.Action)
+	case neato.StateEventChargeThreshold:
+		return fmt.Sprintf("charge crossed %d%% (now %d%%)", ev.Threshold, ev.Current.Details.Charge)
+	case neato.StateEventAlertRaised:
+		return fmt.Sprintf("alert: %s", ev.Current.Alert)
+	case neato.StateEventErrorRaised:
+		return fmt.Sprintf("error: %s", ev.Current.Error)
+	default:
+		return ev.Kind.String()
+	}
+}
+
+func chargeBar(charge int) string {
+	const width = 10
+	filled := charge * width / 100
+	if filled < 0 {
+		filled = 0
+	}
+	if filled > width {
+		filled = width
+	}
+	return fmt.Sprintf("[%s%s] %3d%%", strings.Repeat("█", filled), strings.Repeat("░", width-filled), charge)
+}
+
+type cell struct {
+	text      string
+	highlight bool
+}
+
+// cell returns the value, highlighted if the field changed recently.
+func (row *robotRow) cell(field, value string) cell {
+	t, ok := row.changedAt[field]
+	return cell{text: value, highlight: ok && time.Since(t) < highlightFor}
+}
+
+// writeTable writes the rows aligned in columns. Column widths are computed
+// on the plain text, so highlighting does not break the alignment.
+func writeTable(sb *strings.Builder, rows [][]cell) {
+	var widths []int
+	for _, row := range rows {
+		for i, c := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(c.text); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	for _, row := range rows {
+		for i, c := range row {
+			text := c.text
+			if i < len(row)-1 {
+				text += strings.Repeat(" ", widths[i]-utf8.RuneCountInString(c.text)+2)
+			}
+			if c.highlight {
+				text = "\x1b[1;33m" + text + "\x1b[0m"
+			}
+			sb.WriteString(text)
+		}
+		sb.WriteByte('\n')
+	}
+}
+
+func (d *dashboard) render(f *os.File) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var sb strings.Builder
+	// clear the screen and move the cursor to the top left corner
+	sb.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(&sb, "neato watch - %s - press Ctrl-C to exit\n\n", time.Now().Format("15:04:05"))
+	rows := [][]cell{{
+		{text: "ROBOT"}, {text: "STATE"}, {text: "ACTION"}, {text: "CHARGE"}, {text: "DOCKED"},
+		{text: "CHARGING"}, {text: "ALERT"}, {text: "ERROR"}, {text: "LAST RUN"},
+	}}
+	for _, row := range d.rows {
+		name := cell{text: row.robot.Name}
+		if row.state == nil {
+			status := "connecting..."
+			if row.err != nil {
+				status = "offline"
+			}
+			rows = append(rows, []cell{name, row.cell("online", status), {}, {}, {}, {}, {}, {}, row.cell("lastRun", row.lastRun)})
+			continue
+		}
+		s := row.state
+		alert, errStr := "-", "-"
+		if s.Alert != nil && s.Alert.IsSet() {
+			alert = s.Alert.Info().Description
+		}
+		if s.Error != nil && *s.Error != "" {
+			errStr = s.Error.Info().Description
+		}
+		stateCell := row.cell("state", s.State.String())
+		if row.err != nil {
+			stateCell = row.cell("online", s.State.String()+" (offline)")
+		}
+		rows = append(rows, []cell{
+			name,
+			stateCell,
+			row.cell("action", s.Action.String()),
+			row.cell("charge", chargeBar(s.Details.Charge)),
+			row.cell("docked", yesNo(s.Details.IsDocked)),
+			{text: yesNo(s.Details.IsCharging)},
+			row.cell("alert", alert),
+			row.cell("error", errStr),
+			row.cell("lastRun", row.lastRun),
+		})
+	}
+	writeTable(&sb, rows)
+	sb.WriteString("\nEvents:\n")
+	for _, e := range d.events {
+		sb.WriteString("  " + e + "\n")
+	}
+	fmt.Fprint(f, sb.String())
+}
+
+func initWatchCmd() {
+	watchCmd.Flags().DurationVarP(&flagWatchInterval, "interval", "i", 15*time.Second, "How often to poll every robot")
+	watchCmd.Flags().IntVarP(&flagWatchLogLines, "log-lines", "l", 10, "Number of events to show in the event log")
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/insomniacslk/neato"
+)
+
+func TestDashboardHandlePolled(t *testing.T) {
+	r := &neato.Robot{Serial: "serial", Name: "robot"}
+	d := newDashboard([]*neato.Robot{r}, 10)
+	state := func(charge int) *neato.RobotState {
+		s := neato.RobotState{State: neato.StateIdle}
+		s.Details.Charge = charge
+		return &s
+	}
+
+	d.handle(r, neato.StateEvent{Kind: neato.StateEventInitial, Time: time.Now(), Current: state(50)})
+	d.handle(r, neato.StateEvent{Kind: neato.StateEventPollError, Time: time.Now(), Err: errors.New("offline")})
+	d.handle(r, neato.StateEvent{Kind: neato.StateEventPolled, Time: time.Now(), Previous: state(50), Current: state(53)})
+
+	row := d.bySerial[r.Serial]
+	if row.state.Details.Charge != 53 {
+		t.Errorf("got charge %d, want 53", row.state.Details.Charge)
+	}
+	if row.err != nil {
+		t.Errorf("got error %v after a successful poll", row.err)
+	}
+	// only the poll error is logged
+	if len(d.events) != 1 {
+		t.Errorf("got %d logged events, want 1: %q", len(d.events), d.events)
+	}
+}
+
+func TestCheckWatchFlags(t *testing.T) {
+	for _, tc := range []struct {
+		interval time.Duration
+		logLines int
+		wantErr  bool
+	}{
+		{15 * time.Second, 10, false},
+		{time.Second, 0, false},
+		{0, 10, true},
+		{-time.Second, 10, true},
+		{time.Second, -1, true},
+	} {
+		if err := checkWatchFlags(tc.interval, tc.logLines); (err != nil) != tc.wantErr {
+			t.Errorf("checkWatchFlags(%s, %d) = %v, want error: %v", tc.interval, tc.logLines, err, tc.wantErr)
+		}
+	}
+}
+
+func TestDashboardNoLogLines(t *testing.T) {
+	r := &neato.Robot{Serial: "serial", Name: "robot"}
+	d := newDashboard([]*neato.Robot{r}, 0)
+	d.handle(r, neato.StateEvent{Kind: neato.StateEventStateChanged, Time: time.Now(), Previous: &neato.RobotState{}, Current: &neato.RobotState{State: neato.StateBusy}})
+	if len(d.events) != 0 {
+		t.Errorf("got %d events in the log, want none", len(d.events))
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// newTestWSConn returns a server side connection and the client end of it.
+func newTestWSConn(t *testing.T) (*wsConn, net.Conn) {
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	rw := bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server))
+	return &wsConn{conn: server, rw: rw}, client
+}
+
+// clientFrame encodes a final client frame, masked unless mask is nil.
+// length overrides the encoded payload length if not negative.
+func clientFrame(opcode byte, payload []byte, mask []byte, length int) []byte {
+	n := len(payload)
+	if length >= 0 {
+		n = length
+	}
+	frame := []byte{0x80 | opcode}
+	maskBit := byte(0)
+	if mask != nil {
+		maskBit = 0x80
+	}
+	switch {
+	case n < 126:
+		frame = append(frame, maskBit|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(n))
+	default:
+		frame = append(frame, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(n))
+	}
+	if mask == nil {
+		return append(frame, payload...)
+	}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+func TestReadFrame(t *testing.T) {
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	long := bytes.Repeat([]byte("x"), 300)
+	for _, tc := range []struct {
+		name        string
+		frame       []byte
+		wantOpcode  byte
+		wantPayload []byte
+		wantErr     string
+	}{
+		{"text", clientFrame(wsOpText, []byte("hello"), mask, -1), wsOpText, []byte("hello"), ""},
+		{"empty ping", clientFrame(wsOpPing, nil, mask, -1), wsOpPing, []byte{}, ""},
+		{"16-bit length", clientFrame(wsOpText, long, mask, -1), wsOpText, long, ""},
+		{"close", clientFrame(wsOpClose, []byte{0x03, 0xe8}, mask, -1), wsOpClose, []byte{0x03, 0xe8}, ""},
+		{"unmasked", clientFrame(wsOpText, []byte("hello"), nil, -1), 0, nil, "must be masked"},
+		{"too large", clientFrame(wsOpText, nil, mask, wsMaxPayload+1), 0, nil, "too large"},
+		{"truncated", clientFrame(wsOpText, []byte("hello"), mask, -1)[:8], 0, nil, "EOF"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, client := newTestWSConn(t)
+			go func() {
+				_, _ = client.Write(tc.frame)
+				client.Close()
+			}()
+			opcode, payload, err := c.readFrame()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want one containing '%s'", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if opcode != tc.wantOpcode || !bytes.Equal(payload, tc.wantPayload) {
+				t.Errorf("got frame (%#x, %q), want (%#x, %q)", opcode, payload, tc.wantOpcode, tc.wantPayload)
+			}
+		})
+	}
+}
+
+func TestWriteFrame(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		length     int
+		wantHeader []byte
+	}{
+		{"empty", 0, []byte{0x81, 0}},
+		{"7-bit length", 125, []byte{0x81, 125}},
+		{"16-bit length", 126, []byte{0x81, 126, 0, 126}},
+		{"largest 16-bit length", 0xffff, []byte{0x81, 126, 0xff, 0xff}},
+		{"64-bit length", 0x10000, []byte{0x81, 127, 0, 0, 0, 0, 0, 1, 0, 0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c, client := newTestWSConn(t)
+			payload := bytes.Repeat([]byte("y"), tc.length)
+			errc := make(chan error, 1)
+			go func() {
+				errc <- c.writeFrame(wsOpText, payload)
+			}()
+			got := make([]byte, len(tc.wantHeader)+tc.length)
+			if _, err := io.ReadFull(client, got); err != nil {
+				t.Fatal(err)
+			}
+			if err := <-errc; err != nil {
+				t.Fatalf("writeFrame failed: %v", err)
+			}
+			if !bytes.Equal(got[:len(tc.wantHeader)], tc.wantHeader) {
+				t.Errorf("got header % x, want % x", got[:len(tc.wantHeader)], tc.wantHeader)
+			}
+			if !bytes.Equal(got[len(tc.wantHeader):], payload) {
+				t.Errorf("payload differs")
+			}
+		})
+	}
+}
+
+func TestReadLoopAnswersPingAndClose(t *testing.T) {
+	c, client := newTestWSConn(t)
+	mask := []byte{1, 2, 3, 4}
+	done := make(chan struct{})
+	go c.readLoop(done)
+
+	if _, err := client.Write(clientFrame(wsOpPing, []byte("ping"), mask, -1)); err != nil {
+		t.Fatal(err)
+	}
+	pong := make([]byte, 6)
+	if _, err := io.ReadFull(client, pong); err != nil {
+		t.Fatal(err)
+	}
+	if want := append([]byte{0x80 | wsOpPong, 4}, "ping"...); !bytes.Equal(pong, want) {
+		t.Errorf("got pong % x, want % x", pong, want)
+	}
+
+	if _, err := client.Write(clientFrame(wsOpClose, []byte{0x03, 0xe8}, mask, -1)); err != nil {
+		t.Fatal(err)
+	}
+	closeFrame := make([]byte, 4)
+	if _, err := io.ReadFull(client, closeFrame); err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x80 | wsOpClose, 2, 0x03, 0xe8}; !bytes.Equal(closeFrame, want) {
+		t.Errorf("got close frame % x, want % x", closeFrame, want)
+	}
+	<-done
+}
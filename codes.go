@@ -0,0 +1,181 @@
+package neato
+
+import "fmt"
+
+type Severity int
+
+var (
+	SeverityInfo    Severity = 0
+	SeverityWarning Severity = 1
+	SeverityError   Severity = 2
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// CodeInfo is the catalog entry of a known alert or error code.
+type CodeInfo struct {
+	Severity    Severity
+	Description string
+	Remediation string
+}
+
+// ErrorCode is the error reported by a robot in RobotState.Error, for example
+// "ui_error_brush_stuck". Codes that are not in the catalog are kept as they
+// are.
+type ErrorCode string
+
+// AlertCode is the alert reported by a robot in RobotState.Alert, for example
+// "ui_alert_return_to_base". Codes that are not in the catalog are kept as
+// they are.
+type AlertCode string
+
+var errorCatalog = map[ErrorCode]CodeInfo{
+	"ui_error_battery_overtemp":                      {SeverityError, "Battery is overheating", "Let the robot cool down before using it again."},
+	"ui_error_brush_stuck":                           {SeverityError, "Brush stuck", "Remove the brush and clear any hair or debris around it."},
+	"ui_error_brush_overloaded":                      {SeverityError, "Brush overloaded", "Clean the brush, then move the robot to a lower-pile floor."},
+	"ui_error_bumper_stuck":                          {SeverityError, "Bumper stuck", "Tap the bumper gently to release it."},
+	"ui_error_check_battery_switch":                  {SeverityError, "Check the battery switch", "Make sure the battery switch is turned on."},
+	"ui_error_corrupt_scb":                           {SeverityError, "Internal board corrupted", "Contact customer support."},
+	"ui_error_deck_debris":                           {SeverityError, "Debris in the deck", "Clear the debris from the brush deck."},
+	"ui_error_dflt_app":                              {SeverityError, "Check the app", "Open the Neato app for details."},
+	"ui_error_disconnect_chrg_cable":                 {SeverityError, "Disconnect the charging cable", "Unplug the charging cable from the robot."},
+	"ui_error_disconnect_usb_cable":                  {SeverityError, "Disconnect the USB cable", "Unplug the USB cable from the robot."},
+	"ui_error_dust_bin_missing":                      {SeverityError, "Dust bin missing", "Insert the dust bin."},
+	"ui_error_dust_bin_full":                         {SeverityError, "Dust bin full", "Empty the dust bin and clean the filter."},
+	"ui_error_dust_bin_emptied":                      {SeverityWarning, "Dust bin emptied", "Reinsert the dust bin to continue."},
+	"ui_error_hardware_failure":                      {SeverityError, "Hardware failure", "Restart the robot; contact customer support if it persists."},
+	"ui_error_ldrop_stuck":                           {SeverityError, "Left wheel drop stuck", "Check that the left wheel moves freely."},
+	"ui_error_lwheel_stuck":                          {SeverityError, "Left wheel stuck", "Clear any obstruction around the left wheel."},
+	"ui_error_rdrop_stuck":                           {SeverityError, "Right wheel drop stuck", "Check that the right wheel moves freely."},
+	"ui_error_rwheel_stuck":                          {SeverityError, "Right wheel stuck", "Clear any obstruction around the right wheel."},
+	"ui_error_navigation_backdrop_frontbump":         {SeverityError, "Front bumper pressed while reversing", "Move the robot to an open area."},
+	"ui_error_navigation_backdrop_leftbump":          {SeverityError, "Left bumper pressed while reversing", "Move the robot to an open area."},
+	"ui_error_navigation_backdrop_wheelextended":     {SeverityError, "Wheel extended while reversing", "Move the robot to a flat area."},
+	"ui_error_navigation_falling":                    {SeverityError, "Robot detected a drop", "Move the robot away from stairs or ledges."},
+	"ui_error_navigation_noprogress":                 {SeverityError, "Robot cannot make progress", "Clear the area around the robot."},
+	"ui_error_navigation_origin_unclean":             {SeverityError, "Starting area is not clear", "Clear the area around the charge base."},
+	"ui_error_navigation_pathproblems_returninghome": {SeverityError, "Cannot find a path back to the base", "Carry the robot back to the charge base."},
+	"ui_error_navigation_undockingfailed":            {SeverityError, "Undocking failed", "Clear the area in front of the charge base."},
+	"ui_error_picked_up":                             {SeverityError, "Robot was picked up", "Put the robot back on the floor."},
+	"ui_error_stuck":                                 {SeverityError, "Robot is stuck", "Free the robot and restart cleaning."},
+	"ui_error_unable_to_return_to_base":              {SeverityError, "Unable to return to base", "Carry the robot back to the charge base."},
+	"ui_error_unable_to_see":                         {SeverityError, "Laser sensor blocked", "Clean the laser sensor on top of the robot."},
+	"ui_error_vacuum_slip":                           {SeverityError, "Vacuum slipping", "Check the vacuum fan for obstructions."},
+	"ui_error_vacuum_stuck":                          {SeverityError, "Vacuum stuck", "Check the vacuum fan for obstructions."},
+	"ui_error_warning":                               {SeverityWarning, "Generic warning", "Open the Neato app for details."},
+	"error_battery_overtemp":                         {SeverityError, "Battery is overheating", "Let the robot cool down before using it again."},
+	"dustbin_full":                                   {SeverityError, "Dust bin full", "Empty the dust bin and clean the filter."},
+	"dustbin_missing":                                {SeverityError, "Dust bin missing", "Insert the dust bin."},
+	"maint_brush_stuck":                              {SeverityError, "Brush stuck", "Remove the brush and clear any hair or debris around it."},
+	"maint_brush_overload":                           {SeverityError, "Brush overloaded", "Clean the brush, then move the robot to a lower-pile floor."},
+	"maint_bumper_stuck":                             {SeverityError, "Bumper stuck", "Tap the bumper gently to release it."},
+	"maint_customer_support_qa":                      {SeverityError, "Robot needs service", "Contact customer support."},
+	"maint_vacuum_stuck":                             {SeverityError, "Vacuum stuck", "Check the vacuum fan for obstructions."},
+	"maint_vacuum_slip":                              {SeverityError, "Vacuum slipping", "Check the vacuum fan for obstructions."},
+	"maint_left_drop_stuck":                          {SeverityError, "Left wheel drop stuck", "Check that the left wheel moves freely."},
+	"maint_left_wheel_stuck":                         {SeverityError, "Left wheel stuck", "Clear any obstruction around the left wheel."},
+	"maint_right_drop_stuck":                         {SeverityError, "Right wheel drop stuck", "Check that the right wheel moves freely."},
+	"maint_right_wheel_stuck":                        {SeverityError, "Right wheel stuck", "Clear any obstruction around the right wheel."},
+	"not_on_charge_base":                             {SeverityWarning, "Robot is not on the charge base", "Place the robot on the charge base."},
+	"nav_robot_falling":                              {SeverityError, "Robot detected a drop", "Move the robot away from stairs or ledges."},
+	"nav_no_path":                                    {SeverityError, "No path to the destination", "Clear obstacles or open doors on the way."},
+	"nav_path_problem":                               {SeverityError, "Problem navigating the path", "Clear obstacles or open doors on the way."},
+	"nav_backdrop_frontbump":                         {SeverityError, "Front bumper pressed while reversing", "Move the robot to an open area."},
+	"nav_backdrop_leftbump":                          {SeverityError, "Left bumper pressed while reversing", "Move the robot to an open area."},
+	"nav_backdrop_wheelextended":                     {SeverityError, "Wheel extended while reversing", "Move the robot to a flat area."},
+	"nav_floorplan_zone_path_blocked":                {SeverityError, "Path to the zone is blocked", "Open doors and clear the way to the zone."},
+	"nav_floorplan_zone_wrong_floor":                 {SeverityError, "Zone is on a different floor", "Move the robot to the floor of the zone."},
+	"nav_floorplan_zone_unreachable":                 {SeverityError, "Zone is unreachable", "Open doors and clear the way to the zone."},
+	"nav_mag_sensor":                                 {SeverityError, "Boundary marker confusion", "Check the placement of the boundary markers."},
+	"nav_no_exit":                                    {SeverityError, "Cannot leave the charge base area", "Clear the area around the charge base."},
+	"nav_no_location":                                {SeverityError, "Robot cannot find its location", "Move the robot back to the charge base and restart."},
+	"nav_unable_to_return_to_base":                   {SeverityError, "Unable to return to base", "Carry the robot back to the charge base."},
+}
+
+var alertCatalog = map[AlertCode]CodeInfo{
+	"ui_alert_invalid":                   {SeverityInfo, "No alert", ""},
+	"ui_alert_dust_bin_full":             {SeverityWarning, "Dust bin full", "Empty the dust bin."},
+	"ui_alert_recovering_location":       {SeverityInfo, "Recovering location", "Wait for the robot to relocate itself."},
+	"ui_alert_battery_chargebasecommerr": {SeverityWarning, "Cannot communicate with the charge base", "Check the charge base power and contacts."},
+	"ui_alert_busy_charging":             {SeverityInfo, "Busy charging", "Wait for the robot to charge."},
+	"ui_alert_charging_base":             {SeverityInfo, "Charging on base", ""},
+	"ui_alert_charging_power":            {SeverityInfo, "Charging", ""},
+	"ui_alert_connect_chrg_cable":        {SeverityWarning, "Connect the charging cable", "Plug the charging cable into the robot."},
+	"ui_alert_info_thank_you":            {SeverityInfo, "Thank you", ""},
+	"ui_alert_old_error":                 {SeverityWarning, "A previous error occurred", "Open the Neato app for details."},
+	"ui_alert_return_to_base":            {SeverityInfo, "Returning to base", ""},
+	"ui_alert_return_to_charge":          {SeverityInfo, "Returning to base to recharge", ""},
+	"ui_alert_return_to_start":           {SeverityInfo, "Returning to the starting point", ""},
+	"ui_alert_swupdate_fail":             {SeverityWarning, "Software update failed", "Retry the update from the Neato app."},
+	"ui_alert_timed_out":                 {SeverityWarning, "Operation timed out", "Retry the operation."},
+	"dustbin_full":                       {SeverityWarning, "Dust bin full", "Empty the dust bin."},
+	"maint_brush_change":                 {SeverityInfo, "Brush needs replacing", "Replace the main brush."},
+	"maint_filter_change":                {SeverityInfo, "Filter needs replacing", "Replace the filter."},
+	"clean_completed_to_start":           {SeverityInfo, "Cleaning completed", ""},
+	"clean_incomplete_to_start":          {SeverityWarning, "Cleaning incomplete", "Check the map for areas that could not be reached."},
+	"nav_floorplan_not_created":          {SeverityWarning, "Floor plan was not created", "Run an exploration to create the floor plan."},
+	"nav_floorplan_load_fail":            {SeverityWarning, "Floor plan could not be loaded", "Retry, or recreate the floor plan."},
+	"nav_floorplan_localization_fail":    {SeverityWarning, "Robot could not locate itself on the floor plan", "Start cleaning from the charge base."},
+	"log_upload_failed":                  {SeverityInfo, "Log upload failed", ""},
+}
+
+// Known reports whether the code is in the catalog.
+func (e ErrorCode) Known() bool {
+	_, ok := errorCatalog[e]
+	return ok
+}
+
+// Info returns the catalog entry of the code. Unknown codes are reported as
+// errors described by the raw code.
+func (e ErrorCode) Info() CodeInfo {
+	if info, ok := errorCatalog[e]; ok {
+		return info
+	}
+	return CodeInfo{Severity: SeverityError, Description: string(e)}
+}
+
+func (e ErrorCode) String() string {
+	if !e.Known() {
+		return string(e)
+	}
+	return fmt.Sprintf("%s (%s)", e.Info().Description, string(e))
+}
+
+// Known reports whether the code is in the catalog.
+func (a AlertCode) Known() bool {
+	_, ok := alertCatalog[a]
+	return ok
+}
+
+// Info returns the catalog entry of the code. Unknown codes are reported as
+// warnings described by the raw code.
+func (a AlertCode) Info() CodeInfo {
+	if info, ok := alertCatalog[a]; ok {
+		return info
+	}
+	return CodeInfo{Severity: SeverityWarning, Description: string(a)}
+}
+
+func (a AlertCode) String() string {
+	if !a.Known() {
+		return string(a)
+	}
+	return fmt.Sprintf("%s (%s)", a.Info().Description, string(a))
+}
+
+// IsSet reports whether the alert carries information, since robots report
+// "ui_alert_invalid" when there is no alert.
+func (a AlertCode) IsSet() bool {
+	return a != "" && a != "ui_alert_invalid"
+}
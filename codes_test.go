@@ -0,0 +1,77 @@
+package neato
+
+import "testing"
+
+func TestErrorCode(t *testing.T) {
+	for _, tc := range []struct {
+		code     ErrorCode
+		known    bool
+		severity Severity
+		str      string
+	}{
+		{"ui_error_brush_stuck", true, SeverityError, "Brush stuck (ui_error_brush_stuck)"},
+		{"ui_error_dust_bin_emptied", true, SeverityWarning, "Dust bin emptied (ui_error_dust_bin_emptied)"},
+		{"ui_error_made_up", false, SeverityError, "ui_error_made_up"},
+		{"", false, SeverityError, ""},
+	} {
+		if got := tc.code.Known(); got != tc.known {
+			t.Errorf("%q: Known() = %v, want %v", tc.code, got, tc.known)
+		}
+		info := tc.code.Info()
+		if info.Severity != tc.severity {
+			t.Errorf("%q: got severity %s, want %s", tc.code, info.Severity, tc.severity)
+		}
+		if !tc.known && (info.Description != string(tc.code) || info.Remediation != "") {
+			t.Errorf("%q: got %+v for an unknown code, want the raw code as description", tc.code, info)
+		}
+		if got := tc.code.String(); got != tc.str {
+			t.Errorf("%q: String() = %q, want %q", tc.code, got, tc.str)
+		}
+	}
+}
+
+func TestAlertCode(t *testing.T) {
+	for _, tc := range []struct {
+		code     AlertCode
+		known    bool
+		set      bool
+		severity Severity
+		str      string
+	}{
+		{"ui_alert_return_to_base", true, true, SeverityInfo, "Returning to base (ui_alert_return_to_base)"},
+		{"dustbin_full", true, true, SeverityWarning, "Dust bin full (dustbin_full)"},
+		{"ui_alert_invalid", true, false, SeverityInfo, "No alert (ui_alert_invalid)"},
+		{"ui_alert_made_up", false, true, SeverityWarning, "ui_alert_made_up"},
+		{"", false, false, SeverityWarning, ""},
+	} {
+		if got := tc.code.Known(); got != tc.known {
+			t.Errorf("%q: Known() = %v, want %v", tc.code, got, tc.known)
+		}
+		if got := tc.code.IsSet(); got != tc.set {
+			t.Errorf("%q: IsSet() = %v, want %v", tc.code, got, tc.set)
+		}
+		info := tc.code.Info()
+		if info.Severity != tc.severity {
+			t.Errorf("%q: got severity %s, want %s", tc.code, info.Severity, tc.severity)
+		}
+		if !tc.known && (info.Description != string(tc.code) || info.Remediation != "") {
+			t.Errorf("%q: got %+v for an unknown code, want the raw code as description", tc.code, info)
+		}
+		if got := tc.code.String(); got != tc.str {
+			t.Errorf("%q: String() = %q, want %q", tc.code, got, tc.str)
+		}
+	}
+}
+
+func TestSeverityString(t *testing.T) {
+	for s, want := range map[Severity]string{
+		SeverityInfo:    "info",
+		SeverityWarning: "warning",
+		SeverityError:   "error",
+		Severity(7):     "unknown",
+	} {
+		if got := s.String(); got != want {
+			t.Errorf("Severity(%d).String() = %q, want %q", int(s), got, want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package neato
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Command is a Nucleo robot command. Params returns the value to be sent as
+// the "params" object of the request, or nil if the command takes none.
+type Command interface {
+	Name() string
+	Params() interface{}
+}
+
+// nucleoReqID is used to generate unique request IDs across all robots.
+var nucleoReqID uint64
+
+func nextReqID() string {
+	return strconv.FormatUint(atomic.AddUint64(&nucleoReqID, 1), 10)
+}
+
+type nucleoRequest struct {
+	ReqID  string      `json:"reqId"`
+	Cmd    string      `json:"cmd"`
+	Params interface{} `json:"params,omitempty"`
+}
+
+// Response is the common envelope of every Nucleo response. The full
+// response body is kept so it can be decoded into a command-specific type
+// with Decode.
+type Response struct {
+	Version int             `json:"version"`
+	ReqID   string          `json:"reqId"`
+	Result  Result          `json:"result"`
+	Data    json.RawMessage `json:"data"`
+
+	raw json.RawMessage
+}
+
+func (r *Response) UnmarshalJSON(b []byte) error {
+	type response Response
+	var resp response
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return err
+	}
+	*r = Response(resp)
+	r.raw = append(json.RawMessage(nil), b...)
+	return nil
+}
+
+// Decode unmarshals the full response body into v.
+func (r *Response) Decode(v interface{}) error {
+	if err := json.Unmarshal(r.raw, v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// ResultError is returned when a robot answers a command with a result other
+// than ResultOK.
+type ResultError struct {
+	Cmd    string
+	Result Result
+}
+
+func (e *ResultError) Error() string {
+	return fmt.Sprintf("command '%s' failed: %s", e.Cmd, e.Result)
+}
+
+// Do sends a command to the robot and returns its response. The response
+// request ID must match the one that was sent, and its result must be
+// ResultOK, otherwise an error is returned.
+func (r *Robot) Do(ctx context.Context, cmd Command) (*Response, error) {
+	start := time.Now()
+	resp, err := r.do(ctx, cmd)
+	observeAPICall("nucleo "+cmd.Name(), start, err)
+	return resp, err
+}
+
+func (r *Robot) do(ctx context.Context, cmd Command) (*Response, error) {
+	req := nucleoRequest{
+		ReqID:  nextReqID(),
+		Cmd:    cmd.Name(),
+		Params: cmd.Params(),
+	}
+	var resp Response
+	if err := r.post(ctx, &req, &resp); err != nil {
+		return nil, fmt.Errorf("command '%s' failed: %w", req.Cmd, err)
+	}
+	if resp.ReqID != req.ReqID {
+		return nil, fmt.Errorf("command '%s' failed: response ID '%s' does not match request ID '%s'", req.Cmd, resp.ReqID, req.ReqID)
+	}
+	if _, ok := cmd.(*GetRobotStateCommand); !ok {
+		// any other command may change the state of the robot
+		r.cache.invalidate(cacheKeyState(r.Serial))
+	}
+	if resp.Result != ResultOK {
+		return nil, &ResultError{Cmd: req.Cmd, Result: resp.Result}
+	}
+	return &resp, nil
+}
+
+type GetRobotStateCommand struct{}
+
+func (c *GetRobotStateCommand) Name() string        { return "getRobotState" }
+func (c *GetRobotStateCommand) Params() interface{} { return nil }
+
+// StartCleaningCommand starts house, zone or spot cleaning. Only set the
+// parameters supported by the robot's service version, see
+// Capabilities.StartCleaningCommand and Capabilities.SpotCleaningCommand.
+type StartCleaningCommand struct {
+	Category       Category
+	Mode           *CleaningMode
+	Modifier       *int
+	NavigationMode *NavigationMode
+	BoundaryID     string
+	MapID          string
+	SpotWidth      int
+	SpotHeight     int
+}
+
+func (c *StartCleaningCommand) Name() string { return "startCleaning" }
+func (c *StartCleaningCommand) Params() interface{} {
+	type params struct {
+		Category       string `json:"category"`
+		Mode           *int   `json:"mode,omitempty"`
+		Modifier       *int   `json:"modifier,omitempty"`
+		NavigationMode *int   `json:"navigationMode,omitempty"`
+		BoundaryID     string `json:"boundaryId,omitempty"`
+		MapID          string `json:"mapId,omitempty"`
+		SpotWidth      int    `json:"spotWidth,omitempty"`
+		SpotHeight     int    `json:"spotHeight,omitempty"`
+	}
+	p := params{
+		Category:   strconv.FormatInt(int64(c.Category), 10),
+		Modifier:   c.Modifier,
+		BoundaryID: c.BoundaryID,
+		MapID:      c.MapID,
+		SpotWidth:  c.SpotWidth,
+		SpotHeight: c.SpotHeight,
+	}
+	if c.Mode != nil {
+		mode := int(*c.Mode)
+		p.Mode = &mode
+	}
+	if c.NavigationMode != nil {
+		navMode := int(*c.NavigationMode)
+		p.NavigationMode = &navMode
+	}
+	return &p
+}
+
+type StopCleaningCommand struct{}
+
+func (c *StopCleaningCommand) Name() string        { return "stopCleaning" }
+func (c *StopCleaningCommand) Params() interface{} { return nil }
+
+type PauseCleaningCommand struct{}
+
+func (c *PauseCleaningCommand) Name() string        { return "pauseCleaning" }
+func (c *PauseCleaningCommand) Params() interface{} { return nil }
+
+type ResumeCleaningCommand struct{}
+
+func (c *ResumeCleaningCommand) Name() string        { return "resumeCleaning" }
+func (c *ResumeCleaningCommand) Params() interface{} { return nil }
+
+type SendToBaseCommand struct{}
+
+func (c *SendToBaseCommand) Name() string        { return "sendToBase" }
+func (c *SendToBaseCommand) Params() interface{} { return nil }
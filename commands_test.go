@@ -0,0 +1,99 @@
+package neato
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoRequestIDMismatch(t *testing.T) {
+	f := newFakeCloud(t)
+	robots, err := f.account(nil).Robots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.editResponses(func(cmd string, resp map[string]interface{}) {
+		resp["reqId"] = "not-" + resp["reqId"].(string)
+	})
+	_, err = robots[0].Do(context.Background(), &StopCleaningCommand{})
+	if err == nil || !strings.Contains(err.Error(), "does not match request ID") {
+		t.Fatalf("got error %v, want a request ID mismatch", err)
+	}
+	var resultErr *ResultError
+	if errors.As(err, &resultErr) {
+		t.Errorf("got ResultError %v for a request ID mismatch", err)
+	}
+}
+
+func TestDoResultError(t *testing.T) {
+	f := newFakeCloud(t)
+	robots, err := f.account(nil).Robots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.editResponses(func(cmd string, resp map[string]interface{}) {
+		if cmd == "startCleaning" {
+			resp["result"] = string(ResultNotOnChargeBase)
+		}
+	})
+	_, err = robots[0].Do(context.Background(), &StartCleaningCommand{Category: CategoryNonPersistentMap})
+	var resultErr *ResultError
+	if !errors.As(err, &resultErr) {
+		t.Fatalf("got error %v, want a ResultError", err)
+	}
+	if resultErr.Cmd != "startCleaning" || resultErr.Result != ResultNotOnChargeBase {
+		t.Errorf("got %+v, want command startCleaning and result %s", resultErr, ResultNotOnChargeBase)
+	}
+}
+
+func TestDoInvalidatesState(t *testing.T) {
+	f := newFakeCloud(t)
+	cache := NewCache(CacheConfig{StateTTL: time.Hour})
+	robots, err := f.account(cache).Robots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := robots[0]
+	ctx := context.Background()
+
+	// calls returns how many requests reached the robot while running fn.
+	calls := func(fn func() error) int32 {
+		before := atomic.LoadInt32(&f.nucleoCalls)
+		if err := fn(); err != nil {
+			t.Fatal(err)
+		}
+		return atomic.LoadInt32(&f.nucleoCalls) - before
+	}
+	getState := func() error {
+		_, err := r.State(ctx)
+		return err
+	}
+	if n := calls(getState); n != 1 {
+		t.Fatalf("first State sent %d requests, want 1", n)
+	}
+	if n := calls(getState); n != 0 {
+		t.Fatalf("cached State sent %d requests, want 0", n)
+	}
+	if n := calls(func() error { return r.Pause(ctx) }); n != 1 {
+		t.Fatalf("Pause sent %d requests, want 1", n)
+	}
+	if n := calls(getState); n != 1 {
+		t.Errorf("State after Pause sent %d requests, want 1", n)
+	}
+
+	// a failed command may have changed the state as well
+	f.editResponses(func(cmd string, resp map[string]interface{}) {
+		if cmd == "resumeCleaning" {
+			resp["result"] = string(ResultKO)
+		}
+	})
+	if err := r.Resume(ctx); err == nil {
+		t.Fatal("Resume succeeded, want an error")
+	}
+	if n := calls(getState); n != 1 {
+		t.Errorf("State after a failed Resume sent %d requests, want 1", n)
+	}
+}
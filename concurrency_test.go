@@ -0,0 +1,120 @@
+package neato
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentUse exercises robots and maps shared between goroutines, and
+// is meant to be run with -race.
+func TestConcurrentUse(t *testing.T) {
+	f := newFakeCloud(t)
+	acc := f.account(NewCache(CacheConfig{RobotsTTL: time.Hour, MapsTTL: time.Hour}))
+	ctx := context.Background()
+
+	robots, err := acc.Robots()
+	if err != nil {
+		t.Fatalf("Robots failed: %v", err)
+	}
+	r := robots[0]
+	maps, err := r.Maps()
+	if err != nil {
+		t.Fatalf("Maps failed: %v", err)
+	}
+	// every worker downloads the same map, whose URL is refreshed by every
+	// download
+	shared := maps[0]
+
+	const workers, iterations = 8, 50
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				if _, err := acc.Robots(); err != nil {
+					t.Errorf("Robots failed: %v", err)
+					return
+				}
+				state, err := r.State(ctx)
+				if err != nil {
+					t.Errorf("State failed: %v", err)
+					return
+				}
+				if state.Details.Charge != 80 {
+					t.Errorf("got charge %d, want 80", state.Details.Charge)
+				}
+				if _, err := r.Maps(); err != nil {
+					t.Errorf("Maps failed: %v", err)
+					return
+				}
+				var buf bytes.Buffer
+				if err := shared.Download(ctx, &buf); err != nil {
+					t.Errorf("Download failed: %v", err)
+					return
+				}
+				if !strings.HasPrefix(buf.String(), "image /images/map-1.png") {
+					t.Errorf("got image %q", buf.String())
+				}
+				_ = shared.String()
+				if _, err := json.Marshal(shared); err != nil {
+					t.Errorf("Marshal failed: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMapJSON(t *testing.T) {
+	in := `{"id":"map-1","url":"https://example.com/map.png","url_valid_for_seconds":60,"cleaned_area":1.5}`
+	var m Map
+	if err := json.Unmarshal([]byte(in), &m); err != nil {
+		t.Fatal(err)
+	}
+	if m.ID != "map-1" || m.ImageURL() != "https://example.com/map.png" || m.URL != m.ImageURL() || m.CleanedArea == nil || *m.CleanedArea != 1.5 {
+		t.Fatalf("unexpected map %+v", &m)
+	}
+	out, err := json.Marshal(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(out, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields["url"] != "https://example.com/map.png" || fields["url_valid_for_seconds"] != float64(60) || fields["id"] != "map-1" {
+		t.Errorf("unexpected JSON %s", out)
+	}
+}
+
+func TestMapDownloadRefreshesURL(t *testing.T) {
+	f := newFakeCloud(t)
+	robots, err := f.account(nil).Robots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	maps, err := robots[0].Maps()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := maps[0]
+	fetched := m.URL
+	if !m.URLExpired() {
+		t.Fatal("got a valid URL, want an expired one")
+	}
+	if err := m.Download(context.Background(), io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	// the refreshed URL replaces the one returned with the map, which is
+	// left untouched
+	if m.URL != fetched || m.ImageURL() == fetched {
+		t.Errorf("got URL %s and image URL %s after refreshing %s", m.URL, m.ImageURL(), fetched)
+	}
+}
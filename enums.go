@@ -0,0 +1,236 @@
+package neato
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type EnumFormat int
+
+var (
+	// EnumFormatNames encodes enums as their symbolic names, e.g. "house_cleaning".
+	EnumFormatNames EnumFormat = 0
+	// EnumFormatNumbers encodes enums as the numbers used by the Nucleo API.
+	EnumFormatNumbers EnumFormat = 1
+)
+
+// EnumJSONFormat controls how MarshalJSON encodes State, Action,
+// CleaningMode, NavigationMode and Category values. UnmarshalJSON always
+// accepts both names and numbers, including numbers without a name, while
+// UnmarshalText only accepts known values.
+var EnumJSONFormat = EnumFormatNames
+
+func ParseEnumFormat(s string) (EnumFormat, error) {
+	switch s {
+	case "names":
+		return EnumFormatNames, nil
+	case "numbers":
+		return EnumFormatNumbers, nil
+	default:
+		return 0, fmt.Errorf("invalid enum format '%s', must be one of 'names' or 'numbers'", s)
+	}
+}
+
+var stateNames = map[State]string{
+	StateInvalid: "invalid",
+	StateIdle:    "idle",
+	StateBusy:    "busy",
+	StatePaused:  "paused",
+	StateError:   "error",
+}
+
+var actionNames = map[Action]string{
+	ActionNone:                 "none",
+	ActionHouseCleaning:        "house_cleaning",
+	ActionSpotCleaning:         "spot_cleaning",
+	ActionManualCleaning:       "manual_cleaning",
+	ActionDocking:              "docking",
+	ActionUserMenuActive:       "user_menu_active",
+	ActionSuspendedCleaning:    "suspended_cleaning",
+	ActionUpdating:             "updating",
+	ActionCopyingLogs:          "copying_logs",
+	ActionRecoveringLocation:   "recovering_location",
+	ActionIECTest:              "iec_test",
+	ActionMapCleaning:          "map_cleaning",
+	ActionExploringMap:         "exploring_map",
+	ActionAcquiringMapIDs:      "acquiring_map_ids",
+	ActionCreatingMap:          "creating_map",
+	ActionSuspendedExploration: "suspended_exploration",
+}
+
+var cleaningModeNames = map[CleaningMode]string{
+	CleaningModeEco:   "eco",
+	CleaningModeTurbo: "turbo",
+}
+
+var navigationModeNames = map[NavigationMode]string{
+	NavigationModeNormal:    "normal",
+	NavigationModeExtraCare: "extra_care",
+	NavigationModeDeep:      "deep",
+}
+
+var categoryNames = map[Category]string{
+	CategoryManual:           "manual",
+	CategoryNonPersistentMap: "non_persistent_map",
+	CategorySpot:             "spot",
+	CategoryPersistentMap:    "persistent_map",
+}
+
+func unknownEnum(v int) string {
+	return fmt.Sprintf("unknown(%d)", v)
+}
+
+func enumName[T ~int](names map[T]string, v T) string {
+	if name, ok := names[v]; ok {
+		return name
+	}
+	return unknownEnum(int(v))
+}
+
+// parseEnum accepts a symbolic name or the number of a known value. If
+// allowUnknown is set, it also accepts the number of a value without a name,
+// bare or in the "unknown(N)" form produced by MarshalText: this is how newer
+// firmware values survive a JSON round trip, but user input such as CLI
+// flags must name a value that the robots understand.
+func parseEnum[T ~int](names map[T]string, s string, allowUnknown bool) (T, error) {
+	for v, name := range names {
+		if name == s {
+			return v, nil
+		}
+	}
+	numStr := s
+	if allowUnknown && strings.HasPrefix(s, "unknown(") && strings.HasSuffix(s, ")") {
+		numStr = s[len("unknown(") : len(s)-1]
+	}
+	n, err := strconv.Atoi(numStr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s'", s)
+	}
+	if _, ok := names[T(n)]; !ok && !allowUnknown {
+		return 0, fmt.Errorf("unknown value '%s'", s)
+	}
+	return T(n), nil
+}
+
+func marshalEnumJSON[T ~int](names map[T]string, v T) ([]byte, error) {
+	if EnumJSONFormat == EnumFormatNumbers {
+		return json.Marshal(int(v))
+	}
+	return json.Marshal(enumName(names, v))
+}
+
+func unmarshalEnumJSON[T ~int](names map[T]string, b []byte, v *T) error {
+	if string(b) == "null" {
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		parsed, err := parseEnum(names, s, true)
+		if err != nil {
+			return err
+		}
+		*v = parsed
+		return nil
+	}
+	var n int
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*v = T(n)
+	return nil
+}
+
+// unmarshalEnumText only accepts known values, see parseEnum.
+func unmarshalEnumText[T ~int](names map[T]string, b []byte, v *T) error {
+	parsed, err := parseEnum(names, string(b), false)
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(enumName(stateNames, s)), nil
+}
+
+func (s State) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(stateNames, s)
+}
+
+func (s *State) UnmarshalText(b []byte) error {
+	return unmarshalEnumText(stateNames, b, s)
+}
+
+func (s *State) UnmarshalJSON(b []byte) error {
+	return unmarshalEnumJSON(stateNames, b, s)
+}
+
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(enumName(actionNames, a)), nil
+}
+
+func (a Action) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(actionNames, a)
+}
+
+func (a *Action) UnmarshalText(b []byte) error {
+	return unmarshalEnumText(actionNames, b, a)
+}
+
+func (a *Action) UnmarshalJSON(b []byte) error {
+	return unmarshalEnumJSON(actionNames, b, a)
+}
+
+func (c CleaningMode) MarshalText() ([]byte, error) {
+	return []byte(enumName(cleaningModeNames, c)), nil
+}
+
+func (c CleaningMode) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(cleaningModeNames, c)
+}
+
+func (c *CleaningMode) UnmarshalText(b []byte) error {
+	return unmarshalEnumText(cleaningModeNames, b, c)
+}
+
+func (c *CleaningMode) UnmarshalJSON(b []byte) error {
+	return unmarshalEnumJSON(cleaningModeNames, b, c)
+}
+
+func (n NavigationMode) MarshalText() ([]byte, error) {
+	return []byte(enumName(navigationModeNames, n)), nil
+}
+
+func (n NavigationMode) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(navigationModeNames, n)
+}
+
+func (n *NavigationMode) UnmarshalText(b []byte) error {
+	return unmarshalEnumText(navigationModeNames, b, n)
+}
+
+func (n *NavigationMode) UnmarshalJSON(b []byte) error {
+	return unmarshalEnumJSON(navigationModeNames, b, n)
+}
+
+func (c Category) MarshalText() ([]byte, error) {
+	return []byte(enumName(categoryNames, c)), nil
+}
+
+func (c Category) MarshalJSON() ([]byte, error) {
+	return marshalEnumJSON(categoryNames, c)
+}
+
+func (c *Category) UnmarshalText(b []byte) error {
+	return unmarshalEnumText(categoryNames, b, c)
+}
+
+func (c *Category) UnmarshalJSON(b []byte) error {
+	return unmarshalEnumJSON(categoryNames, b, c)
+}
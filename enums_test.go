@@ -0,0 +1,118 @@
+package neato
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEnumJSONRoundTrip(t *testing.T) {
+	defer func(format EnumFormat) { EnumJSONFormat = format }(EnumJSONFormat)
+
+	for _, tc := range []struct {
+		format EnumFormat
+		mode   CleaningMode
+		want   string
+	}{
+		{EnumFormatNames, CleaningModeTurbo, `"turbo"`},
+		{EnumFormatNames, CleaningMode(17), `"unknown(17)"`},
+		{EnumFormatNumbers, CleaningModeTurbo, `2`},
+		{EnumFormatNumbers, CleaningMode(17), `17`},
+	} {
+		EnumJSONFormat = tc.format
+		b, err := json.Marshal(tc.mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tc.want {
+			t.Errorf("format %d, mode %d: got %s, want %s", tc.format, int(tc.mode), b, tc.want)
+		}
+		var got CleaningMode
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("format %d: cannot decode %s: %v", tc.format, b, err)
+		} else if got != tc.mode {
+			t.Errorf("format %d: %s decoded to %d, want %d", tc.format, b, int(got), int(tc.mode))
+		}
+	}
+}
+
+func TestEnumUnmarshalJSON(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    Action
+		wantErr bool
+	}{
+		{`"house_cleaning"`, ActionHouseCleaning, false},
+		{`1`, ActionHouseCleaning, false},
+		{`"1"`, ActionHouseCleaning, false},
+		{`42`, Action(42), false},
+		{`"42"`, Action(42), false},
+		{`"unknown(42)"`, Action(42), false},
+		{`null`, ActionNone, false},
+		{`"vacuuming"`, ActionNone, true},
+		{`"unknown(x)"`, ActionNone, true},
+		{`true`, ActionNone, true},
+	} {
+		var got Action
+		err := json.Unmarshal([]byte(tc.in), &got)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: got error %v, want error: %v", tc.in, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: got %d, want %d", tc.in, int(got), int(tc.want))
+		}
+	}
+}
+
+func TestEnumText(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    CleaningMode
+		wantErr bool
+	}{
+		{"eco", CleaningModeEco, false},
+		{"turbo", CleaningModeTurbo, false},
+		{"2", CleaningModeTurbo, false},
+		// unlike JSON, text is user input and must name a known value
+		{"17", 0, true},
+		{"unknown(17)", 0, true},
+		{"Turbo", 0, true},
+		{"", 0, true},
+	} {
+		var got CleaningMode
+		err := got.UnmarshalText([]byte(tc.in))
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: got error %v, want error: %v", tc.in, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%q: got %d, want %d", tc.in, int(got), int(tc.want))
+		}
+	}
+
+	// known values survive a text round trip, unknown ones are named
+	for _, n := range []NavigationMode{NavigationModeNormal, NavigationModeExtraCare, NavigationModeDeep} {
+		b, err := n.MarshalText()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got NavigationMode
+		if err := got.UnmarshalText(b); err != nil || got != n {
+			t.Errorf("%s: got (%d, %v), want %d", b, int(got), err, int(n))
+		}
+	}
+	if b, _ := Category(9).MarshalText(); string(b) != "unknown(9)" {
+		t.Errorf("got %s, want unknown(9)", b)
+	}
+}
+
+func TestParseEnumFormat(t *testing.T) {
+	for in, want := range map[string]EnumFormat{"names": EnumFormatNames, "numbers": EnumFormatNumbers} {
+		if got, err := ParseEnumFormat(in); err != nil || got != want {
+			t.Errorf("%s: got (%d, %v), want %d", in, got, err, want)
+		}
+	}
+	if _, err := ParseEnumFormat("Names"); err == nil {
+		t.Error("Names: expected an error")
+	}
+}
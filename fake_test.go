@@ -0,0 +1,172 @@
+package neato
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeCloud is a local Beehive and Nucleo server with a single robot.
+type fakeCloud struct {
+	t         *testing.T
+	srv       *httptest.Server
+	serial    string
+	secretKey string
+
+	mapsCalls   int32
+	nucleoCalls int32
+
+	mu    sync.Mutex
+	state map[string]interface{}
+	// mapStats is the "stats" object returned by the maps endpoint.
+	mapStats map[string]interface{}
+	// offline makes Nucleo answer like for a robot that is not connected.
+	offline bool
+	// edit, if set, may change the Nucleo response to a command before it
+	// is sent.
+	edit func(cmd string, resp map[string]interface{})
+}
+
+func newFakeCloud(t *testing.T) *fakeCloud {
+	f := fakeCloud{
+		t:         t,
+		serial:    "fake-serial",
+		secretKey: "fake-secret",
+		state: map[string]interface{}{
+			"state":             1,
+			"action":            0,
+			"details":           map[string]interface{}{"charge": 80, "isDocked": true, "dockHasBeenSeen": true},
+			"availableServices": map[string]interface{}{"houseCleaning": "basic-4", "maps": "basic-2"},
+			"meta":              map[string]interface{}{"modelName": "botvacD7Connected", "firmware": "4.5.3"},
+		},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/me/robots", f.handleRobots)
+	mux.HandleFunc("/users/me/robots/"+f.serial+"/maps", f.handleMaps)
+	mux.HandleFunc("/vendors/neato/robots/"+f.serial+"/messages", f.handleNucleo)
+	mux.HandleFunc("/images/", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "image %s", req.URL.Path)
+	})
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return &f
+}
+
+// account returns an account logged in to the fake cloud.
+func (f *fakeCloud) account(cache *Cache) *Account {
+	header := url.Values{}
+	header.Set("Authorization", "Token token=fake")
+	session := NewPasswordSession(f.srv.URL, &header)
+	session.keepNucleoPort = true
+	return NewAccountWithCache(session, cache)
+}
+
+func (f *fakeCloud) setState(key string, value interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.state[key] = value
+}
+
+func (f *fakeCloud) setMapStats(stats map[string]interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.mapStats = stats
+}
+
+func (f *fakeCloud) setOffline(offline bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.offline = offline
+}
+
+func (f *fakeCloud) editResponses(edit func(cmd string, resp map[string]interface{})) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.edit = edit
+}
+
+func (f *fakeCloud) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		f.t.Errorf("failed to write response: %v", err)
+	}
+}
+
+func (f *fakeCloud) handleRobots(w http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("Authorization") == "" {
+		http.Error(w, "not logged in", http.StatusUnauthorized)
+		return
+	}
+	f.writeJSON(w, []map[string]interface{}{{
+		"serial":     f.serial,
+		"name":       "Fake",
+		"secret_key": f.secretKey,
+		"nucleo_url": f.srv.URL,
+	}})
+}
+
+// handleMaps returns a new image URL on every call, valid for zero seconds,
+// so that every Download refreshes it.
+func (f *fakeCloud) handleMaps(w http.ResponseWriter, req *http.Request) {
+	n := atomic.AddInt32(&f.mapsCalls, 1)
+	f.mu.Lock()
+	stats := f.mapStats
+	f.mu.Unlock()
+	if stats == nil {
+		stats = map[string]interface{}{}
+	}
+	f.writeJSON(w, map[string]interface{}{
+		"stats": stats,
+		"maps": []map[string]interface{}{{
+			"id":                    "map-1",
+			"url":                   fmt.Sprintf("%s/images/map-1.png?v=%d", f.srv.URL, n),
+			"url_valid_for_seconds": 0,
+			"start_at":              "2026-10-19T10:00:00Z",
+			"end_at":                "2026-10-19T11:00:00Z",
+			"cleaned_area":          12.5,
+		}},
+	})
+}
+
+func (f *fakeCloud) handleNucleo(w http.ResponseWriter, req *http.Request) {
+	atomic.AddInt32(&f.nucleoCalls, 1)
+	f.mu.Lock()
+	offline := f.offline
+	f.mu.Unlock()
+	if offline {
+		http.Error(w, `{"message":"Robot not online"}`, http.StatusNotFound)
+		return
+	}
+	if err := VerifyNucleoRequest(f.serial, f.secretKey, req); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var nreq nucleoRequest
+	if err := json.NewDecoder(req.Body).Decode(&nreq); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	resp := map[string]interface{}{
+		"version": 1,
+		"reqId":   nreq.ReqID,
+		"result":  "ok",
+		"data":    map[string]interface{}{},
+	}
+	f.mu.Lock()
+	if strings.HasPrefix(nreq.Cmd, "get") {
+		for k, v := range f.state {
+			resp[k] = v
+		}
+	}
+	if f.edit != nil {
+		f.edit(nreq.Cmd, resp)
+	}
+	f.mu.Unlock()
+	f.writeJSON(w, resp)
+}
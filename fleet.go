@@ -0,0 +1,182 @@
+package neato
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultConcurrency is the number of robots queried in parallel when no
+// concurrency is specified.
+const DefaultConcurrency = 4
+
+// ForEachRobot calls fn for every robot of the account, running at most
+// concurrency calls in parallel. A failing robot does not stop the others:
+// the returned map holds the error of every robot for which fn failed, keyed
+// by serial number. The error is only set if the robots cannot be fetched.
+func (a *Account) ForEachRobot(ctx context.Context, concurrency int, fn func(ctx context.Context, r *Robot) error) (map[string]error, error) {
+	robots, err := a.RobotsContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get robots: %w", err)
+	}
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, concurrency)
+		errs = make(map[string]error)
+	)
+	for _, r := range robots {
+		wg.Add(1)
+		go func(r *Robot) {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				mu.Lock()
+				errs[r.Serial] = ctx.Err()
+				mu.Unlock()
+				return
+			}
+			defer func() { <-sem }()
+			if err := fn(ctx, r); err != nil {
+				mu.Lock()
+				errs[r.Serial] = err
+				mu.Unlock()
+			}
+		}(r)
+	}
+	wg.Wait()
+	return errs, nil
+}
+
+// StateResult is the state of a robot, or the error that prevented fetching
+// it.
+type StateResult struct {
+	Robot *Robot
+	State *RobotState
+	Err   error
+}
+
+// States fetches the state of every robot concurrently, keyed by serial
+// number.
+func (a *Account) States(ctx context.Context, concurrency int) (map[string]*StateResult, error) {
+	var mu sync.Mutex
+	results := make(map[string]*StateResult)
+	errs, err := a.ForEachRobot(ctx, concurrency, func(ctx context.Context, r *Robot) error {
+		state, err := r.State(ctx)
+		mu.Lock()
+		results[r.Serial] = &StateResult{Robot: r, State: state, Err: err}
+		mu.Unlock()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// robots that never ran because the context was cancelled
+	for serial, err := range errs {
+		if _, ok := results[serial]; !ok {
+			robot, _ := a.RobotBySerial(serial)
+			results[serial] = &StateResult{Robot: robot, Err: err}
+		}
+	}
+	return results, nil
+}
+
+// MapsResult is the maps of a robot, or the error that prevented fetching
+// them.
+type MapsResult struct {
+	Robot *Robot
+	Maps  []*Map
+	Stats *MapStats
+	Err   error
+}
+
+// MapsByRobot fetches the maps of every robot concurrently, keyed by serial
+// number.
+func (a *Account) MapsByRobot(ctx context.Context, concurrency int) (map[string]*MapsResult, error) {
+	var mu sync.Mutex
+	results := make(map[string]*MapsResult)
+	errs, err := a.ForEachRobot(ctx, concurrency, func(ctx context.Context, r *Robot) error {
+		maps, stats, err := r.MapHistoryContext(ctx)
+		mu.Lock()
+		results[r.Serial] = &MapsResult{Robot: r, Maps: maps, Stats: stats, Err: err}
+		mu.Unlock()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for serial, err := range errs {
+		if _, ok := results[serial]; !ok {
+			robot, _ := a.RobotBySerial(serial)
+			results[serial] = &MapsResult{Robot: robot, Err: err}
+		}
+	}
+	return results, nil
+}
+
+// RobotErrors holds the errors of the robots for which an operation failed,
+// keyed by serial number. errors.Is and errors.As match any of them.
+type RobotErrors map[string]error
+
+// serials returns the serial numbers of the failed robots, sorted.
+func (e RobotErrors) serials() []string {
+	serials := make([]string, 0, len(e))
+	for serial := range e {
+		serials = append(serials, serial)
+	}
+	sort.Strings(serials)
+	return serials
+}
+
+func (e RobotErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, serial := range e.serials() {
+		msgs = append(msgs, fmt.Sprintf("robot '%s': %v", serial, e[serial]))
+	}
+	return fmt.Sprintf("%d robot(s) failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors sorted by serial number.
+func (e RobotErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, serial := range e.serials() {
+		errs = append(errs, e[serial])
+	}
+	return errs
+}
+
+// Is reports whether any of the errors matches target.
+func (e RobotErrors) Is(target error) bool {
+	for _, err := range e.Unwrap() {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error, in serial number order, that matches target.
+func (e RobotErrors) As(target interface{}) bool {
+	for _, err := range e.Unwrap() {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinRobotErrors combines per-robot errors into a single one, or returns
+// nil if there are none.
+func joinRobotErrors(errs map[string]error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return RobotErrors(errs)
+}
@@ -0,0 +1,173 @@
+package neato
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFleetAccount returns an account with n robots, whose maps endpoint is
+// served by maps.
+func newFleetAccount(t *testing.T, n int, maps http.HandlerFunc) *Account {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users/me/robots", func(w http.ResponseWriter, req *http.Request) {
+		var robots []map[string]interface{}
+		for i := 0; i < n; i++ {
+			robots = append(robots, map[string]interface{}{"serial": fmt.Sprintf("serial-%02d", i), "name": fmt.Sprintf("robot %d", i)})
+		}
+		if err := json.NewEncoder(w).Encode(robots); err != nil {
+			t.Errorf("failed to write robots: %v", err)
+		}
+	})
+	if maps != nil {
+		mux.HandleFunc("/users/me/robots/", maps)
+	}
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	header := url.Values{}
+	header.Set("Authorization", "Token token=fake")
+	return NewAccountWithCache(NewPasswordSession(srv.URL, &header), nil)
+}
+
+func TestForEachRobotConcurrency(t *testing.T) {
+	const robots = 10
+	acc := newFleetAccount(t, robots, nil)
+	for _, tc := range []struct {
+		concurrency int
+		want        int
+	}{
+		{1, 1},
+		{3, 3},
+		{0, DefaultConcurrency},
+		{-1, DefaultConcurrency},
+		{50, robots},
+	} {
+		var running, peak, calls int32
+		errs, err := acc.ForEachRobot(context.Background(), tc.concurrency, func(ctx context.Context, r *Robot) error {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			atomic.AddInt32(&calls, 1)
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		})
+		if err != nil || len(errs) != 0 {
+			t.Fatalf("concurrency %d: got (%v, %v), want no errors", tc.concurrency, errs, err)
+		}
+		if calls != robots {
+			t.Errorf("concurrency %d: fn called %d times, want %d", tc.concurrency, calls, robots)
+		}
+		if int(peak) != tc.want {
+			t.Errorf("concurrency %d: %d calls ran in parallel, want %d", tc.concurrency, peak, tc.want)
+		}
+	}
+}
+
+func TestForEachRobotErrors(t *testing.T) {
+	acc := newFleetAccount(t, 4, nil)
+	errs, err := acc.ForEachRobot(context.Background(), 2, func(ctx context.Context, r *Robot) error {
+		if strings.HasSuffix(r.Serial, "1") || strings.HasSuffix(r.Serial, "3") {
+			return fmt.Errorf("failed %s", r.Serial)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 2 || errs["serial-01"] == nil || errs["serial-03"] == nil {
+		t.Errorf("got errors %v, want serial-01 and serial-03", errs)
+	}
+
+	// robots that are still waiting for a slot when the context is done
+	// get the context error without running
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls int32
+	errs, err = acc.ForEachRobot(ctx, 1, func(ctx context.Context, r *Robot) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			cancel()
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 4 {
+		t.Errorf("got errors %v, want one for every robot", errs)
+	}
+	for serial, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("robot '%s': got error %v, want %v", serial, err, context.Canceled)
+		}
+	}
+}
+
+func TestMapsByRobotContext(t *testing.T) {
+	block := make(chan struct{})
+	t.Cleanup(func() { close(block) })
+	acc := newFleetAccount(t, 2, func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-block:
+		case <-req.Context().Done():
+		}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results, err := acc.MapsByRobot(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("MapsByRobot took %s, it should stop when the context is done", elapsed)
+	}
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	for serial, res := range results {
+		if !errors.Is(res.Err, context.DeadlineExceeded) {
+			t.Errorf("robot '%s': got error %v, want %v", serial, res.Err, context.DeadlineExceeded)
+		}
+	}
+}
+
+func TestRobotErrors(t *testing.T) {
+	if err := joinRobotErrors(nil); err != nil {
+		t.Errorf("got %v for no errors, want nil", err)
+	}
+	err := joinRobotErrors(map[string]error{
+		"c": fmt.Errorf("state failed: %w", ErrRobotOffline),
+		"a": errors.New("boom"),
+		"b": fmt.Errorf("start failed: %w", &ResultError{Cmd: "startCleaning", Result: ResultKO}),
+	})
+	want := "3 robot(s) failed: robot 'a': boom; robot 'b': start failed: command 'startCleaning' failed: ko; robot 'c': state failed: " + ErrRobotOffline.Error()
+	if err.Error() != want {
+		t.Errorf("got error\n%s\nwant\n%s", err, want)
+	}
+	if !errors.Is(err, ErrRobotOffline) {
+		t.Errorf("errors.Is(%v, ErrRobotOffline) = false", err)
+	}
+	if errors.Is(err, context.Canceled) {
+		t.Errorf("errors.Is(%v, context.Canceled) = true", err)
+	}
+	var resultErr *ResultError
+	if !errors.As(err, &resultErr) || resultErr.Result != ResultKO {
+		t.Errorf("errors.As(%v) did not find the ResultError", err)
+	}
+	var robotErrs RobotErrors
+	if !errors.As(fmt.Errorf("discover: %w", err), &robotErrs) || len(robotErrs) != 3 {
+		t.Errorf("errors.As did not find the RobotErrors")
+	}
+}
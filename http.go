@@ -2,8 +2,10 @@ package neato
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,8 +13,62 @@ import (
 	"time"
 )
 
-func httpGet(uri string, header *url.Values, skipVerify bool, response interface{}) error {
-	req, err := http.NewRequest(http.MethodGet, uri, nil)
+// errHTTPForbidden is returned by httpDownload on HTTP 403, which is what the
+// map image storage returns for expired URLs.
+var errHTTPForbidden = errors.New("HTTP 403 Forbidden")
+
+// APICall describes a completed request to the Neato cloud or to a robot.
+type APICall struct {
+	// Endpoint identifies the request without robot-specific parts, like
+	// "GET users/me/robots/{serial}/maps" or "nucleo getRobotState".
+	Endpoint string
+	Duration time.Duration
+	Err      error
+	// FastFail is set if the request was not sent, because the circuit
+	// breaker of the robot is open. Err is then ErrRobotOffline.
+	FastFail bool
+}
+
+// APICallHook, if not nil, is called after every API request, for example to
+// collect metrics. It is called concurrently, and must be set before any
+// request is made.
+var APICallHook func(APICall)
+
+// observeAPICall reports a request started at start to APICallHook.
+func observeAPICall(endpoint string, start time.Time, err error) {
+	if APICallHook == nil {
+		return
+	}
+	var fastFail *fastFailError
+	APICallHook(APICall{Endpoint: endpoint, Duration: time.Since(start), Err: err, FastFail: errors.As(err, &fastFail)})
+}
+
+// httpStatusError is returned by httpDo when the server responds with an
+// HTTP error status.
+type httpStatusError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("expected HTTP 2xx/3xx, got %s", e.Status)
+}
+
+func httpGet(ctx context.Context, uri string, header *url.Values, skipVerify bool, response interface{}) error {
+	return httpDo(ctx, http.MethodGet, uri, header, nil, skipVerify, response)
+}
+
+func httpPost(ctx context.Context, uri string, header *url.Values, data []byte, skipVerify bool, response interface{}) error {
+	return httpDo(ctx, http.MethodPost, uri, header, data, skipVerify, response)
+}
+
+func httpDo(ctx context.Context, method, uri string, header *url.Values, data []byte, skipVerify bool, response interface{}) error {
+	var reqBody io.Reader
+	if data != nil {
+		reqBody = bytes.NewBuffer(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, uri, reqBody)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -23,13 +79,17 @@ func httpGet(uri string, header *url.Values, skipVerify bool, response interface
 			}
 		}
 	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: skipVerify},
 	}
 	client := &http.Client{Transport: tr, Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("HTTP GET failed: %w", err)
+		return fmt.Errorf("HTTP %s failed: %w", method, err)
 	}
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
@@ -37,7 +97,7 @@ func httpGet(uri string, header *url.Values, skipVerify bool, response interface
 		return fmt.Errorf("failed to read HTTP body: %w", err)
 	}
 	if resp.StatusCode >= 400 {
-		return fmt.Errorf("expected HTTP 2xx/3xx, got %s", resp.Status)
+		return &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: body}
 	}
 
 	if err := json.Unmarshal(body, response); err != nil {
@@ -46,43 +106,26 @@ func httpGet(uri string, header *url.Values, skipVerify bool, response interface
 	return nil
 }
 
-func httpPost(uri string, header *url.Values, dataMap map[string]interface{}, skipVerify bool, response interface{}) error {
-	data, err := json.Marshal(dataMap)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request data to JSON: %w", err)
-	}
-	req, err := http.NewRequest(http.MethodPost, uri, bytes.NewBuffer(data))
+// httpDownload copies the body of a GET request to w without decoding it.
+func httpDownload(ctx context.Context, uri string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
-	if header != nil {
-		for k, vv := range *header {
-			for _, v := range vv {
-				req.Header.Set(k, v)
-			}
-		}
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: skipVerify},
-	}
-	client := &http.Client{Transport: tr, Timeout: 10 * time.Second}
+	client := &http.Client{Timeout: 60 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("HTTP POST failed: %w", err)
+		return fmt.Errorf("HTTP GET failed: %w", err)
 	}
 	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read HTTP body: %w", err)
+	if resp.StatusCode == http.StatusForbidden {
+		return errHTTPForbidden
 	}
 	if resp.StatusCode >= 400 {
 		return fmt.Errorf("expected HTTP 2xx/3xx, got %s", resp.Status)
 	}
-
-	if err := json.Unmarshal(body, response); err != nil {
-		return fmt.Errorf("failed to unmarshal JSON response: %w", err)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to read HTTP body: %w", err)
 	}
 	return nil
 }
@@ -0,0 +1,246 @@
+package neato
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultPollInterval is how often the robot state is polled while waiting
+// for the robot.
+const DefaultPollInterval = 15 * time.Second
+
+// waitForState polls the robot state every interval until cond returns true
+// or an error, or until the context is done.
+func (r *Robot) waitForState(ctx context.Context, interval time.Duration, cond func(*RobotState) (bool, error)) (*RobotState, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		state, err := r.State(ctx)
+		if err != nil {
+			return nil, err
+		}
+		done, err := cond(state)
+		if err != nil {
+			return state, err
+		}
+		if done {
+			return state, nil
+		}
+		select {
+		case <-ctx.Done():
+			return state, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ErrJobTimeout is returned by JobQueue.Run when the robot does not become
+// idle or does not recharge in time.
+var ErrJobTimeout = errors.New("timed out waiting for the robot")
+
+// waitForStateTimeout is like waitForState, but gives up with ErrJobTimeout
+// after timeout. A timeout of zero or less waits forever.
+func (r *Robot) waitForStateTimeout(ctx context.Context, interval, timeout time.Duration, cond func(*RobotState) (bool, error)) (*RobotState, error) {
+	if timeout <= 0 {
+		return r.waitForState(ctx, interval, cond)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	state, err := r.waitForState(waitCtx, interval, cond)
+	if err != nil && ctx.Err() == nil && errors.Is(waitCtx.Err(), context.DeadlineExceeded) {
+		return state, fmt.Errorf("%w after %s", ErrJobTimeout, timeout)
+	}
+	return state, err
+}
+
+// isDone reports whether the robot has finished what it was doing and is
+// back to base, or idle where it stopped if it has no base.
+func isDone(s *RobotState) bool {
+	return s.State == StateIdle && (s.Details.IsDocked || !s.Details.DockHasBeenSeen)
+}
+
+// stateError returns an error if the robot is in error state.
+func stateError(s *RobotState) error {
+	if s.State != StateError {
+		return nil
+	}
+	if s.Error != nil {
+		return fmt.Errorf("robot is in error state: %s", s.Error)
+	}
+	return fmt.Errorf("robot is in error state")
+}
+
+// Job is a zone or house cleaning to be run by a JobQueue.
+type Job struct {
+	Name    string
+	Robot   *Robot
+	Options *CleaningOptions
+}
+
+type JobEventKind int
+
+var (
+	JobEventWaitingForRobot JobEventKind = 0
+	JobEventRecharging      JobEventKind = 1
+	JobEventStarted         JobEventKind = 2
+	JobEventProgress        JobEventKind = 3
+	JobEventFinished        JobEventKind = 4
+)
+
+func (k JobEventKind) String() string {
+	switch k {
+	case JobEventWaitingForRobot:
+		return "waiting for robot"
+	case JobEventRecharging:
+		return "recharging"
+	case JobEventStarted:
+		return "started"
+	case JobEventProgress:
+		return "progress"
+	case JobEventFinished:
+		return "finished"
+	default:
+		return unknownEnum(int(k))
+	}
+}
+
+type JobEvent struct {
+	Index int
+	Job   *Job
+	Kind  JobEventKind
+	State *RobotState
+	// Progress is set for JobEventProgress events.
+	Progress *Progress
+	// Summary is set for JobEventFinished events.
+	Summary *RunSummary
+}
+
+// JobQueue runs cleaning jobs one after the other, since the robot firmware
+// can only clean one zone per start. Before every job it waits for the robot
+// to be idle, and sends it to recharge if its charge is below MinCharge.
+type JobQueue struct {
+	Jobs []*Job
+	// MinCharge is the battery percentage below which the robot recharges
+	// before starting the next job.
+	MinCharge int
+	// ResumeCharge is the battery percentage at which a recharging robot
+	// can start the next job. If it is below MinCharge, MinCharge is used
+	// instead, so that the robot is never released before it is above
+	// MinCharge.
+	ResumeCharge int
+	PollInterval time.Duration
+	// StartTimeout is how long to wait for the robot to start cleaning
+	// after the start command.
+	StartTimeout time.Duration
+	// IdleTimeout is how long to wait for the robot to finish what it is
+	// doing before starting a job. Zero means no limit.
+	IdleTimeout time.Duration
+	// RechargeTimeout is how long to wait for the robot to recharge up to
+	// ResumeCharge. Zero means no limit.
+	RechargeTimeout time.Duration
+	// OnEvent, if not nil, is called for every job transition.
+	OnEvent func(JobEvent)
+}
+
+func NewJobQueue() *JobQueue {
+	return &JobQueue{
+		MinCharge:       30,
+		ResumeCharge:    80,
+		PollInterval:    DefaultPollInterval,
+		StartTimeout:    DefaultStartTimeout,
+		IdleTimeout:     2 * time.Hour,
+		RechargeTimeout: 4 * time.Hour,
+	}
+}
+
+// resumeCharge returns ResumeCharge, raised to MinCharge if it is lower.
+func (q *JobQueue) resumeCharge() int {
+	if q.ResumeCharge < q.MinCharge {
+		return q.MinCharge
+	}
+	return q.ResumeCharge
+}
+
+func (q *JobQueue) Add(name string, robot *Robot, opts *CleaningOptions) {
+	q.Jobs = append(q.Jobs, &Job{Name: name, Robot: robot, Options: opts})
+}
+
+// AddRoom adds a job to clean a room with the given cleaning mode.
+func (q *JobQueue) AddRoom(robot *Robot, room *Room, mode CleaningMode, navMode NavigationMode) {
+	opts := room.CleaningOptions()
+	opts.CleaningMode = mode
+	opts.NavigationMode = navMode
+	q.Add(room.Name, robot, opts)
+}
+
+func (q *JobQueue) emit(ev JobEvent) {
+	if q.OnEvent != nil {
+		ev.Job = q.Jobs[ev.Index]
+		q.OnEvent(ev)
+	}
+}
+
+// Run runs all the jobs in order, and stops at the first failure.
+func (q *JobQueue) Run(ctx context.Context) error {
+	for idx, job := range q.Jobs {
+		if err := q.runJob(ctx, idx, job); err != nil {
+			return fmt.Errorf("job #%d '%s' failed: %w", idx+1, job.Name, err)
+		}
+	}
+	return nil
+}
+
+func (q *JobQueue) runJob(ctx context.Context, idx int, job *Job) error {
+	interval := q.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	// wait for the robot to finish what it's doing
+	state, err := job.Robot.State(ctx)
+	if err != nil {
+		return err
+	}
+	if !isDone(state) {
+		q.emit(JobEvent{Index: idx, Kind: JobEventWaitingForRobot, State: state})
+		state, err = job.Robot.waitForStateTimeout(ctx, interval, q.IdleTimeout, func(s *RobotState) (bool, error) {
+			return isDone(s), stateError(s)
+		})
+		if err != nil {
+			return fmt.Errorf("robot did not become idle: %w", err)
+		}
+	}
+
+	// recharge if needed
+	if state.Details.Charge < q.MinCharge {
+		q.emit(JobEvent{Index: idx, Kind: JobEventRecharging, State: state})
+		if !state.Details.IsDocked {
+			if _, err := job.Robot.Do(ctx, &SendToBaseCommand{}); err != nil {
+				return fmt.Errorf("failed to send robot to base: %w", err)
+			}
+		}
+		if _, err := job.Robot.waitForStateTimeout(ctx, interval, q.RechargeTimeout, func(s *RobotState) (bool, error) {
+			return s.Details.IsDocked && s.Details.Charge >= q.resumeCharge(), stateError(s)
+		}); err != nil {
+			return fmt.Errorf("robot did not recharge: %w", err)
+		}
+	}
+
+	wait := NewWaitOptions()
+	wait.StartTimeout = q.StartTimeout
+	summary, err := job.Robot.StartAndWait(ctx, job.Options, wait, func(p Progress) {
+		if p.Phase == RunPhaseStarting {
+			q.emit(JobEvent{Index: idx, Kind: JobEventStarted})
+			return
+		}
+		q.emit(JobEvent{Index: idx, Kind: JobEventProgress, State: p.State, Progress: &p})
+	})
+	// a missing map does not make the job fail, the room was cleaned
+	if err != nil && !errors.Is(err, ErrRunMapUnavailable) {
+		return err
+	}
+	q.emit(JobEvent{Index: idx, Kind: JobEventFinished, Summary: summary})
+	return nil
+}
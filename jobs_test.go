@@ -0,0 +1,86 @@
+package neato
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJobQueueTimeouts(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		state   int
+		charge  int
+		idle    time.Duration
+		charged time.Duration
+		wantErr string
+	}{
+		{"robot stays busy", 2, 80, 50 * time.Millisecond, time.Hour, "robot did not become idle"},
+		{"robot does not recharge", 1, 10, time.Hour, 50 * time.Millisecond, "robot did not recharge"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeCloud(t)
+			f.setState("state", tc.state)
+			f.setState("details", map[string]interface{}{"charge": tc.charge, "isDocked": true, "dockHasBeenSeen": true})
+			robots, err := f.account(nil).Robots()
+			if err != nil {
+				t.Fatal(err)
+			}
+			q := NewJobQueue()
+			q.PollInterval = 10 * time.Millisecond
+			q.IdleTimeout = tc.idle
+			q.RechargeTimeout = tc.charged
+			q.Add("room", robots[0], NewCleaningOptions())
+
+			err = q.Run(context.Background())
+			if !errors.Is(err, ErrJobTimeout) {
+				t.Fatalf("got error %v, want %v", err, ErrJobTimeout)
+			}
+			if got := err.Error(); !strings.Contains(got, tc.wantErr) {
+				t.Errorf("got error '%s', want one containing '%s'", got, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestJobQueueCanceled(t *testing.T) {
+	f := newFakeCloud(t)
+	f.setState("state", 2)
+	robots, err := f.account(nil).Robots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := NewJobQueue()
+	q.PollInterval = 10 * time.Millisecond
+	q.Add("room", robots[0], NewCleaningOptions())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	// the caller giving up is not a job timeout
+	if err := q.Run(ctx); errors.Is(err, ErrJobTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestJobQueueResumeChargeBelowMinCharge(t *testing.T) {
+	f := newFakeCloud(t)
+	f.setState("details", map[string]interface{}{"charge": 85, "isDocked": true, "dockHasBeenSeen": true})
+	robots, err := f.account(nil).Robots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := NewJobQueue()
+	q.PollInterval = 10 * time.Millisecond
+	q.MinCharge = 90
+	q.ResumeCharge = 80
+	q.RechargeTimeout = 50 * time.Millisecond
+	q.Add("room", robots[0], NewCleaningOptions())
+
+	// at 85% the robot is above ResumeCharge but still below MinCharge, so
+	// it must keep recharging
+	err = q.Run(context.Background())
+	if !errors.Is(err, ErrJobTimeout) || !strings.Contains(err.Error(), "robot did not recharge") {
+		t.Fatalf("got error %v, want the recharge to time out", err)
+	}
+}
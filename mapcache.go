@@ -0,0 +1,59 @@
+package neato
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// MapCache stores downloaded map images on disk, keyed by map ID. Map IDs
+// are unique and map images never change, so cached entries never expire.
+type MapCache struct {
+	Dir string
+}
+
+func NewMapCache(dir string) *MapCache {
+	return &MapCache{Dir: dir}
+}
+
+// Path returns the path where the image of the given map is cached.
+func (c *MapCache) Path(mapID string) string {
+	return filepath.Join(c.Dir, filepath.Base(mapID)+".png")
+}
+
+// Has reports whether the image of the given map is in the cache.
+func (c *MapCache) Has(mapID string) bool {
+	_, err := os.Stat(c.Path(mapID))
+	return err == nil
+}
+
+// Fetch returns the path of the cached image of m, downloading it first if
+// it is not in the cache yet.
+func (c *MapCache) Fetch(ctx context.Context, m *Map) (string, error) {
+	p := c.Path(m.ID)
+	if c.Has(m.ID) {
+		return p, nil
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory '%s': %w", c.Dir, err)
+	}
+	// download to a temporary file first, so that an interrupted download
+	// never ends up in the cache.
+	tmp, err := os.CreateTemp(c.Dir, filepath.Base(m.ID)+".*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := m.Download(ctx, tmp); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write '%s': %w", tmp.Name(), err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return "", fmt.Errorf("failed to move map to cache: %w", err)
+	}
+	return p, nil
+}
@@ -1,14 +1,39 @@
 package neato
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
+	"sync"
+	"time"
 )
 
+// Map is a cleaning run of a robot. Download may refresh the image URL while
+// other goroutines use the map, so the current URL is only available through
+// ImageURL and URLExpired.
 type Map struct {
-	Version                        *int     `json:"version"`
-	ID                             string   `json:"id"`
-	URL                            string   `json:"url"`
+	robot *Robot
+
+	// mu guards image and fetchedAt.
+	mu sync.Mutex
+	// image, once Download refreshed the URL, replaces URL and
+	// URLValidForSeconds.
+	image     *mapImage
+	fetchedAt time.Time
+
+	Version *int   `json:"version"`
+	ID      string `json:"id"`
+	// URL is the image URL returned with the map.
+	//
+	// Deprecated: Download may refresh the URL without updating this field,
+	// use ImageURL instead.
+	URL string `json:"url"`
+	// URLValidForSeconds is how long URL is valid after the map was fetched.
+	//
+	// Deprecated: use URLExpired instead.
 	URLValidForSeconds             *int     `json:"url_valid_for_seconds"`
 	RunID                          *string  `json:"run_id"`
 	Status                         *string  `json:"status"`
@@ -32,10 +57,88 @@ type Map struct {
 	Delocalized                    *bool    `json:"delocalized"`
 	GeneratedAt                    *string  `json:"generated_at"`
 	PersistentMapID                *string  `json:"persistent_map_id"`
-	ValidAsPersistentMap           *bool    `json:"calid_as_persistent_map"`
+	ValidAsPersistentMap           *bool    `json:"valid_as_persistent_map"`
 	NavigationMode                 *int     `json:"navigation_mode"`
 }
 
+// mapImage is a refreshed image URL of a map.
+type mapImage struct {
+	url      string
+	validFor *int
+}
+
+// currentImage returns the image URL of the map, its validity and when it
+// was fetched.
+func (m *Map) currentImage() (mapImage, time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.image != nil {
+		return *m.image, m.fetchedAt
+	}
+	return mapImage{url: m.URL, validFor: m.URLValidForSeconds}, m.fetchedAt
+}
+
+// ImageURL returns the current URL of the map image.
+func (m *Map) ImageURL() string {
+	img, _ := m.currentImage()
+	return img.url
+}
+
+// URLExpired reports whether the map image URL is no longer valid.
+func (m *Map) URLExpired() bool {
+	img, fetchedAt := m.currentImage()
+	if img.validFor == nil || fetchedAt.IsZero() {
+		return false
+	}
+	return time.Since(fetchedAt) >= time.Duration(*img.validFor)*time.Second
+}
+
+// refreshURL fetches the robot's maps again and updates the image URL of
+// this map.
+func (m *Map) refreshURL(ctx context.Context) error {
+	if m.robot == nil {
+		return fmt.Errorf("map '%s' is not associated to a robot, cannot refresh its URL", m.ID)
+	}
+	maps, err := m.robot.RefreshMaps(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to refresh maps: %w", err)
+	}
+	for _, fresh := range maps {
+		if fresh == m {
+			return nil
+		}
+		if fresh.ID == m.ID {
+			img, fetchedAt := fresh.currentImage()
+			m.mu.Lock()
+			m.image, m.fetchedAt = &img, fetchedAt
+			m.mu.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("map '%s' not found after refreshing maps for robot '%s'", m.ID, m.robot.Serial)
+}
+
+// Download writes the map image to w. If the image URL has expired, or the
+// server rejects it, the URL is refreshed once via Robot.RefreshMaps.
+func (m *Map) Download(ctx context.Context, w io.Writer) error {
+	if m.URLExpired() {
+		if err := m.refreshURL(ctx); err != nil {
+			return err
+		}
+	}
+	err := httpDownload(ctx, m.ImageURL(), w)
+	if errors.Is(err, errHTTPForbidden) && m.robot != nil {
+		if err := m.refreshURL(ctx); err != nil {
+			return err
+		}
+		err = httpDownload(ctx, m.ImageURL(), w)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download map '%s': %w", m.ID, err)
+	}
+	return nil
+}
+
 func (m *Map) String() string {
 	cleanedArea := "<not set>"
 	if m.CleanedArea != nil {
@@ -45,5 +148,98 @@ func (m *Map) String() string {
 	if m.Error != nil {
 		errStr = *m.Error
 	}
-	return fmt.Sprintf("ID: '%s', URL: %s, Error: %s, Cleaned area: %s sqm", m.ID, m.URL, errStr, cleanedArea)
+	return fmt.Sprintf("ID: '%s', URL: %s, Error: %s, Cleaned area: %s sqm", m.ID, m.ImageURL(), errStr, cleanedArea)
+}
+
+// MapStats summarizes the cleaning history of a robot.
+type MapStats struct {
+	Runs                  int
+	CleanedArea           float64
+	CleaningTime          time.Duration
+	PauseTime             time.Duration
+	ErrorTime             time.Duration
+	SuspendedCleaningTime time.Duration
+	RechargeCount         int
+	FirstRun              time.Time
+	LastRun               time.Time
+	// Server holds the numeric values reported by the maps endpoint, if
+	// any. They are not reflected in the totals above, which only cover the
+	// maps in the list.
+	Server ServerMapStats `json:",omitempty"`
+	// Raw is the "stats" object returned by the maps endpoint, including
+	// fields that are not decoded into Server.
+	Raw json.RawMessage `json:",omitempty"`
+}
+
+// ServerMapStats holds the numeric values of the "stats" object returned by
+// the maps endpoint, keyed by their JSON name. The object is not documented,
+// so its values are kept as reported instead of being merged into the totals
+// computed from the map list. Values that are not numbers are only available
+// in MapStats.Raw.
+type ServerMapStats map[string]float64
+
+// DecodeServerMapStats decodes the numeric values of the "stats" object of
+// the maps endpoint. An empty or null object, or one without numeric values,
+// decodes to nil.
+func DecodeServerMapStats(raw json.RawMessage) (ServerMapStats, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode map stats: %w", err)
+	}
+	var stats ServerMapStats
+	for k, v := range fields {
+		if n, ok := v.(float64); ok {
+			if stats == nil {
+				stats = make(ServerMapStats)
+			}
+			stats[k] = n
+		}
+	}
+	return stats, nil
+}
+
+// NewMapStats computes the statistics of the given maps. Timestamps that
+// cannot be parsed are ignored.
+func NewMapStats(maps []*Map) *MapStats {
+	var stats MapStats
+	for _, m := range maps {
+		stats.Runs++
+		if m.CleanedArea != nil {
+			stats.CleanedArea += *m.CleanedArea
+		}
+		if d, err := m.CleaningDuration(); err == nil {
+			stats.CleaningTime += d
+		}
+		stats.PauseTime += m.PauseDuration()
+		stats.ErrorTime += m.ErrorDuration()
+		stats.SuspendedCleaningTime += m.SuspendedCleaningDuration()
+		if m.SuspendedCleaningChargingCount != nil {
+			stats.RechargeCount += *m.SuspendedCleaningChargingCount
+		}
+		if start, err := m.StartTime(); err == nil && !start.IsZero() {
+			if stats.FirstRun.IsZero() || start.Before(stats.FirstRun) {
+				stats.FirstRun = start
+			}
+			if start.After(stats.LastRun) {
+				stats.LastRun = start
+			}
+		}
+	}
+	return &stats
+}
+
+// AverageCleanedArea returns the cleaned area per run, in square meters.
+func (s *MapStats) AverageCleanedArea() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return s.CleanedArea / float64(s.Runs)
+}
+
+func (s *MapStats) String() string {
+	return fmt.Sprintf("Runs: %d, Cleaned area: %s sqm, Cleaning time: %s, Paused: %s, In error: %s, Recharges: %d",
+		s.Runs, strconv.FormatFloat(s.CleanedArea, 'f', 2, 64), s.CleaningTime, s.PauseTime, s.ErrorTime, s.RechargeCount)
 }
@@ -0,0 +1,47 @@
+package maps
+
+import "image"
+
+// overlap counts the covered pixels that a and b, moved by off, have in
+// common.
+func overlap(a, b *Mask, off image.Point) int {
+	r := a.Rect.Intersect(b.Rect.Add(off))
+	n := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if a.At(x, y) && b.At(x-off.X, y-off.Y) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// bestOffset searches the offsets within radius around center for the one
+// that maximizes the overlap of a and b.
+func bestOffset(a, b *Mask, center image.Point, radius int) image.Point {
+	best, bestScore := center, -1
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			off := center.Add(image.Pt(dx, dy))
+			if score := overlap(a, b, off); score > bestScore {
+				best, bestScore = off, score
+			}
+		}
+	}
+	return best
+}
+
+// Align returns the translation to apply to m so that it best overlaps ref,
+// searching offsets up to maxOffset pixels in each direction. Images of runs
+// over the same persistent map share rotation and scale, so a translation is
+// enough to align them.
+func Align(ref, m *Mask, maxOffset int) image.Point {
+	const factor = 4
+	if maxOffset < factor*2 {
+		return bestOffset(ref, m, image.Point{}, maxOffset)
+	}
+	// coarse search on downsampled masks, then refine at full resolution
+	coarse := bestOffset(ref.downsample(factor), m.downsample(factor), image.Point{}, maxOffset/factor)
+	return bestOffset(ref, m, coarse.Mul(factor), factor)
+}
@@ -0,0 +1,100 @@
+package maps
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+var (
+	// DiffColorBoth marks the area covered by both runs.
+	DiffColorBoth = color.NRGBA{160, 160, 160, 255}
+	// DiffColorOnlyA marks the area covered only by the first run.
+	DiffColorOnlyA = color.NRGBA{230, 40, 40, 255}
+	// DiffColorOnlyB marks the area covered only by the second run.
+	DiffColorOnlyB = color.NRGBA{40, 180, 40, 255}
+)
+
+// Diff is the comparison of the cleaned areas of two runs. Areas are in
+// pixels of the map images.
+type Diff struct {
+	// Offset is the translation applied to the second image to align it
+	// to the first one.
+	Offset image.Point
+	Both   int
+	OnlyA  int
+	OnlyB  int
+
+	a, b *Mask
+}
+
+// NewDiff aligns b to a and compares their cleaned areas. If covered is nil,
+// DefaultCovered is used.
+func NewDiff(a, b image.Image, covered CoveredFunc, maxOffset int) *Diff {
+	ma := CoverageMask(a, covered)
+	mb := CoverageMask(b, covered)
+	off := Align(ma, mb, maxOffset)
+	mb = mb.Translate(off)
+	d := Diff{Offset: off, a: ma, b: mb}
+	r := d.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			inA, inB := ma.At(x, y), mb.At(x, y)
+			switch {
+			case inA && inB:
+				d.Both++
+			case inA:
+				d.OnlyA++
+			case inB:
+				d.OnlyB++
+			}
+		}
+	}
+	return &d
+}
+
+// AreaA returns the area covered by the first run.
+func (d *Diff) AreaA() int {
+	return d.Both + d.OnlyA
+}
+
+// AreaB returns the area covered by the second run.
+func (d *Diff) AreaB() int {
+	return d.Both + d.OnlyB
+}
+
+// Bounds returns the union of the bounds of the two aligned images.
+func (d *Diff) Bounds() image.Rectangle {
+	return d.a.Rect.Union(d.b.Rect)
+}
+
+// Image renders the comparison using DiffColorBoth, DiffColorOnlyA and
+// DiffColorOnlyB. Areas covered by neither run are transparent.
+func (d *Diff) Image() image.Image {
+	r := d.Bounds()
+	img := image.NewNRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			inA, inB := d.a.At(x, y), d.b.At(x, y)
+			var c color.NRGBA
+			switch {
+			case inA && inB:
+				c = DiffColorBoth
+			case inA:
+				c = DiffColorOnlyA
+			case inB:
+				c = DiffColorOnlyB
+			default:
+				continue
+			}
+			img.SetNRGBA(x-r.Min.X, y-r.Min.Y, c)
+		}
+	}
+	return img
+}
+
+// WritePNG encodes the comparison image as PNG.
+func (d *Diff) WritePNG(w io.Writer) error {
+	return png.Encode(w, d.Image())
+}
@@ -0,0 +1,97 @@
+package maps
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	size := image.Pt(16, 16)
+	for _, tc := range []struct {
+		name               string
+		a, b               image.Image
+		maxOffset          int
+		offset             image.Point
+		both, onlyA, onlyB int
+	}{
+		{
+			name:      "identical",
+			a:         testRunImage(size, image.Rect(2, 2, 10, 6)),
+			b:         testRunImage(size, image.Rect(2, 2, 10, 6)),
+			maxOffset: 4,
+			both:      32,
+		},
+		{
+			name:      "disjoint",
+			a:         testRunImage(size, image.Rect(0, 0, 4, 4)),
+			b:         testRunImage(size, image.Rect(10, 10, 16, 12)),
+			maxOffset: 0,
+			onlyA:     16,
+			onlyB:     12,
+		},
+		{
+			name:      "partial overlap",
+			a:         testRunImage(size, image.Rect(0, 0, 8, 4)),
+			b:         testRunImage(size, image.Rect(4, 0, 12, 4)),
+			maxOffset: 0,
+			both:      16,
+			onlyA:     16,
+			onlyB:     16,
+		},
+		{
+			name:      "moved",
+			a:         testRunImage(size, image.Rect(2, 2, 10, 6)),
+			b:         testRunImage(size, image.Rect(5, 3, 13, 7)),
+			maxOffset: 8,
+			offset:    image.Pt(-3, -1),
+			both:      32,
+		},
+		{
+			name:      "empty",
+			a:         testRunImage(size),
+			b:         testRunImage(size),
+			maxOffset: 0,
+		},
+	} {
+		d := NewDiff(tc.a, tc.b, nil, tc.maxOffset)
+		if d.Offset != tc.offset {
+			t.Errorf("%s: got offset %v, want %v", tc.name, d.Offset, tc.offset)
+		}
+		if d.Both != tc.both || d.OnlyA != tc.onlyA || d.OnlyB != tc.onlyB {
+			t.Errorf("%s: got both %d, only A %d, only B %d, want %d, %d, %d", tc.name, d.Both, d.OnlyA, d.OnlyB, tc.both, tc.onlyA, tc.onlyB)
+		}
+		if d.AreaA() != tc.both+tc.onlyA || d.AreaB() != tc.both+tc.onlyB {
+			t.Errorf("%s: got areas %d and %d", tc.name, d.AreaA(), d.AreaB())
+		}
+	}
+}
+
+func TestDiffImage(t *testing.T) {
+	size := image.Pt(8, 4)
+	d := NewDiff(testRunImage(size, image.Rect(0, 0, 4, 4)), testRunImage(size, image.Rect(2, 0, 6, 4)), nil, 0)
+	img := d.Image()
+	for _, tc := range []struct {
+		x    int
+		want string
+	}{
+		{0, "only A"},
+		{2, "both"},
+		{4, "only B"},
+		{7, "none"},
+	} {
+		var got string
+		switch img.At(tc.x, 0) {
+		case DiffColorBoth:
+			got = "both"
+		case DiffColorOnlyA:
+			got = "only A"
+		case DiffColorOnlyB:
+			got = "only B"
+		default:
+			got = "none"
+		}
+		if got != tc.want {
+			t.Errorf("pixel (%d, 0) is %s, want %s", tc.x, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,420 @@
+package maps
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/insomniacslk/neato"
+)
+
+// VectorMap is the vectorized cleaned area of a map image together with the
+// boundaries of its persistent map. Coordinates are in pixels of the map
+// image, with the origin at the top left corner.
+type VectorMap struct {
+	MapID      string
+	Width      int
+	Height     int
+	Cleaned    []image.Rectangle
+	Boundaries []*neato.Boundary
+}
+
+// NewVectorMap vectorizes the cleaned area of img. If covered is nil,
+// DefaultCovered is used. Boundaries are drawn over the whole image, so img
+// must be the persistent map image they belong to; see
+// NewFloorPlanVectorMap for the image of a run.
+func NewVectorMap(mapID string, img image.Image, covered CoveredFunc, boundaries []*neato.Boundary) *VectorMap {
+	mask := CoverageMask(img, covered)
+	return &VectorMap{
+		MapID:      mapID,
+		Width:      mask.Rect.Dx(),
+		Height:     mask.Rect.Dy(),
+		Cleaned:    mask.Rectangles(),
+		Boundaries: boundaries,
+	}
+}
+
+// NewFloorPlanVectorMap vectorizes the cleaned area of a run over a
+// persistent map in the frame of the persistent map image, which is the
+// frame the boundaries are normalized to. The run image is aligned to
+// floorPlan like the images of a Heatmap, searching offsets up to maxOffset
+// pixels, and the cleaned area outside of floorPlan is dropped. If covered
+// is nil, DefaultCovered is used.
+func NewFloorPlanVectorMap(mapID string, run, floorPlan image.Image, covered CoveredFunc, maxOffset int, boundaries []*neato.Boundary) *VectorMap {
+	ref := CoverageMask(floorPlan, covered)
+	m := CoverageMask(run, covered)
+	m = m.Translate(Align(ref, m, maxOffset))
+	clipped := NewMask(ref.Rect)
+	for y := ref.Rect.Min.Y; y < ref.Rect.Max.Y; y++ {
+		for x := ref.Rect.Min.X; x < ref.Rect.Max.X; x++ {
+			clipped.Set(x, y, m.At(x, y))
+		}
+	}
+	return &VectorMap{
+		MapID:      mapID,
+		Width:      ref.Rect.Dx(),
+		Height:     ref.Rect.Dy(),
+		Cleaned:    clipped.Rectangles(),
+		Boundaries: boundaries,
+	}
+}
+
+// toPixels converts normalized boundary vertices to pixel coordinates.
+func (v *VectorMap) toPixels(p [2]float64) (float64, float64) {
+	return p[0] * float64(v.Width), p[1] * float64(v.Height)
+}
+
+const defaultBoundaryColor = "#e03030"
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// WriteSVG writes the map as SVG. Boundaries are written as polygon and
+// polyline elements with class "boundary", and can be read back with
+// ReadBoundariesSVG.
+func (v *VectorMap) WriteSVG(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(&sb, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" data-map-id=\"%s\">\n", v.Width, v.Height, v.Width, v.Height, xmlEscape(v.MapID))
+	if len(v.Cleaned) > 0 {
+		sb.WriteString("  <path id=\"cleaned\" fill=\"#4a90d9\" stroke=\"none\" d=\"")
+		for _, r := range v.Cleaned {
+			fmt.Fprintf(&sb, "M%d %dh%dv%dh%dz", r.Min.X, r.Min.Y, r.Dx(), r.Dy(), -r.Dx())
+		}
+		sb.WriteString("\"/>\n")
+	}
+	for _, b := range v.Boundaries {
+		points := make([]string, 0, len(b.Vertices))
+		for _, p := range b.Vertices {
+			x, y := v.toPixels(p)
+			points = append(points, formatFloat(x)+","+formatFloat(y))
+		}
+		color := b.Color
+		if color == "" {
+			color = defaultBoundaryColor
+		}
+		elem, fill := "polygon", color
+		if b.Type == neato.BoundaryTypePolyline {
+			elem, fill = "polyline", "none"
+		}
+		fmt.Fprintf(&sb, "  <%s class=\"boundary\" id=\"%s\" data-name=\"%s\" data-enabled=\"%v\" data-color=\"%s\" stroke=\"%s\" fill=\"%s\" fill-opacity=\"0.3\" stroke-width=\"2\" points=\"%s\"/>\n",
+			elem, xmlEscape(b.ID), xmlEscape(b.Name), b.Enabled, xmlEscape(b.Color), xmlEscape(color), xmlEscape(fill), strings.Join(points, " "))
+	}
+	sb.WriteString("</svg>\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func xmlEscape(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// svgShapes are the SVG elements that draw something. Boundaries must be
+// polygon or polyline elements, any other shape is rejected rather than
+// silently dropped.
+var svgShapes = map[string]bool{
+	"path": true, "rect": true, "circle": true, "ellipse": true, "line": true,
+	"polygon": true, "polyline": true, "use": true, "image": true, "text": true,
+}
+
+// svgContainers hold elements that are not rendered directly, so their
+// shapes are not boundaries.
+var svgContainers = map[string]bool{
+	"defs": true, "clipPath": true, "mask": true, "pattern": true, "marker": true, "symbol": true,
+}
+
+// ReadBoundariesSVG reads the persistent map ID and the boundaries written by
+// WriteSVG, possibly after they were edited in a drawing tool. Vertices are
+// normalized to the view box of the SVG document, or to its size if it has
+// no view box.
+//
+// Since the boundaries read replace all the boundaries of the map, anything
+// that cannot be converted exactly is an error: shapes other than the
+// exported cleaned area and boundary polygons and polylines, and transform
+// attributes.
+func ReadBoundariesSVG(r io.Reader) (string, []*neato.Boundary, error) {
+	dec := xml.NewDecoder(r)
+	var (
+		mapID       string
+		minX, minY  float64
+		width       float64
+		height      float64
+		boundaries  []*neato.Boundary
+		hiddenDepth int
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to parse SVG: %w", err)
+		}
+		if end, ok := tok.(xml.EndElement); ok {
+			if hiddenDepth > 0 && isSVGElement(end.Name) {
+				hiddenDepth--
+			}
+			continue
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if !isSVGElement(el.Name) {
+			// elements of other namespaces, like the ones added by Inkscape,
+			// are editor metadata
+			if err := dec.Skip(); err != nil {
+				return "", nil, fmt.Errorf("failed to parse SVG: %w", err)
+			}
+			continue
+		}
+		if hiddenDepth > 0 || svgContainers[el.Name.Local] {
+			hiddenDepth++
+			continue
+		}
+		attrs := make(map[string]string)
+		for _, a := range el.Attr {
+			if a.Name.Space == "" {
+				attrs[a.Name.Local] = a.Value
+			}
+		}
+		name := el.Name.Local
+		if attrs["transform"] != "" {
+			return "", nil, fmt.Errorf("unsupported transform on <%s id=\"%s\">, apply the transforms to the shapes before saving", name, attrs["id"])
+		}
+		switch {
+		case name == "svg":
+			mapID = attrs["data-map-id"]
+			if vb := strings.Fields(strings.ReplaceAll(attrs["viewBox"], ",", " ")); len(vb) > 0 {
+				if len(vb) != 4 {
+					return "", nil, fmt.Errorf("invalid SVG view box '%s'", attrs["viewBox"])
+				}
+				var vals [4]float64
+				for i, f := range vb {
+					if vals[i], err = strconv.ParseFloat(f, 64); err != nil {
+						return "", nil, fmt.Errorf("invalid SVG view box '%s'", attrs["viewBox"])
+					}
+				}
+				minX, minY, width, height = vals[0], vals[1], vals[2], vals[3]
+			} else {
+				if width, err = strconv.ParseFloat(attrs["width"], 64); err != nil {
+					return "", nil, fmt.Errorf("invalid SVG width '%s'", attrs["width"])
+				}
+				if height, err = strconv.ParseFloat(attrs["height"], 64); err != nil {
+					return "", nil, fmt.Errorf("invalid SVG height '%s'", attrs["height"])
+				}
+			}
+			if width <= 0 || height <= 0 {
+				return "", nil, fmt.Errorf("invalid SVG size %sx%s", formatFloat(width), formatFloat(height))
+			}
+		case name == "path" && attrs["id"] == "cleaned":
+			// the cleaned area written by WriteSVG
+		case (name == "polygon" || name == "polyline") && attrs["class"] == "boundary":
+			if width == 0 || height == 0 {
+				return "", nil, fmt.Errorf("boundary '%s' found before the SVG size", attrs["id"])
+			}
+			b := neato.Boundary{
+				ID:      attrs["id"],
+				Type:    neato.BoundaryTypePolygon,
+				Name:    attrs["data-name"],
+				Color:   attrs["stroke"],
+				Enabled: attrs["data-enabled"] != "false",
+			}
+			// boundaries without a color are exported with the default one
+			if attrs["data-color"] == "" && b.Color == defaultBoundaryColor {
+				b.Color = ""
+			}
+			if name == "polyline" {
+				b.Type = neato.BoundaryTypePolyline
+			}
+			for _, pt := range strings.Fields(strings.ReplaceAll(attrs["points"], ", ", ",")) {
+				xy := strings.Split(pt, ",")
+				if len(xy) != 2 {
+					return "", nil, fmt.Errorf("invalid point '%s' in boundary '%s'", pt, b.ID)
+				}
+				x, errX := strconv.ParseFloat(xy[0], 64)
+				y, errY := strconv.ParseFloat(xy[1], 64)
+				if errX != nil || errY != nil {
+					return "", nil, fmt.Errorf("invalid point '%s' in boundary '%s'", pt, b.ID)
+				}
+				b.Vertices = append(b.Vertices, [2]float64{(x - minX) / width, (y - minY) / height})
+			}
+			boundaries = append(boundaries, &b)
+		case svgShapes[name]:
+			return "", nil, fmt.Errorf("unsupported <%s id=\"%s\">, boundaries must be <polygon> or <polyline> elements with class=\"boundary\"", name, attrs["id"])
+		}
+	}
+	return mapID, boundaries, nil
+}
+
+// isSVGElement reports whether name is in the SVG namespace, or in no
+// namespace at all.
+func isSVGElement(name xml.Name) bool {
+	return name.Space == "" || name.Space == "http://www.w3.org/2000/svg"
+}
+
+type geoJSONGeometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	ID         string                 `json:"id,omitempty"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Neato    *geoJSONMeta     `json:"neato,omitempty"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+// geoJSONMeta is a foreign member holding the information needed to
+// normalize coordinates back when importing.
+type geoJSONMeta struct {
+	MapID  string `json:"map_id"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// toGeo converts pixel coordinates to GeoJSON ones, where y grows upwards.
+func (v *VectorMap) toGeo(x, y float64) [2]float64 {
+	return [2]float64{x, float64(v.Height) - y}
+}
+
+// WriteGeoJSON writes the map as a GeoJSON feature collection in a local,
+// unitless coordinate system where one unit is one pixel of the map image
+// and y grows upwards. Boundaries can be read back with
+// ReadBoundariesGeoJSON.
+func (v *VectorMap) WriteGeoJSON(w io.Writer) error {
+	fc := geoJSONFeatureCollection{
+		Type:  "FeatureCollection",
+		Neato: &geoJSONMeta{MapID: v.MapID, Width: v.Width, Height: v.Height},
+	}
+	if len(v.Cleaned) > 0 {
+		polygons := make([][][][2]float64, 0, len(v.Cleaned))
+		for _, r := range v.Cleaned {
+			x0, y0, x1, y1 := float64(r.Min.X), float64(r.Min.Y), float64(r.Max.X), float64(r.Max.Y)
+			ring := [][2]float64{v.toGeo(x0, y0), v.toGeo(x0, y1), v.toGeo(x1, y1), v.toGeo(x1, y0), v.toGeo(x0, y0)}
+			polygons = append(polygons, [][][2]float64{ring})
+		}
+		fc.Features = append(fc.Features, geoJSONFeature{
+			Type:       "Feature",
+			ID:         "cleaned",
+			Geometry:   geoJSONGeometry{Type: "MultiPolygon", Coordinates: polygons},
+			Properties: map[string]interface{}{"kind": "cleaned", "map_id": v.MapID},
+		})
+	}
+	for _, b := range v.Boundaries {
+		coords := make([][2]float64, 0, len(b.Vertices)+1)
+		for _, p := range b.Vertices {
+			coords = append(coords, v.toGeo(v.toPixels(p)))
+		}
+		geom := geoJSONGeometry{Type: "LineString", Coordinates: coords}
+		if b.Type != neato.BoundaryTypePolyline {
+			if len(coords) > 0 && coords[0] != coords[len(coords)-1] {
+				coords = append(coords, coords[0])
+			}
+			geom = geoJSONGeometry{Type: "Polygon", Coordinates: [][][2]float64{coords}}
+		}
+		fc.Features = append(fc.Features, geoJSONFeature{
+			Type:     "Feature",
+			ID:       b.ID,
+			Geometry: geom,
+			Properties: map[string]interface{}{
+				"kind":    "boundary",
+				"name":    b.Name,
+				"color":   b.Color,
+				"enabled": b.Enabled,
+			},
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&fc)
+}
+
+// ReadBoundariesGeoJSON reads the persistent map ID and the boundaries
+// written by WriteGeoJSON, possibly after they were edited in a GIS tool.
+func ReadBoundariesGeoJSON(r io.Reader) (string, []*neato.Boundary, error) {
+	var fc struct {
+		Neato    *geoJSONMeta `json:"neato"`
+		Features []struct {
+			ID       string `json:"id"`
+			Geometry struct {
+				Type        string          `json:"type"`
+				Coordinates json.RawMessage `json:"coordinates"`
+			} `json:"geometry"`
+			Properties struct {
+				Kind    string `json:"kind"`
+				Name    string `json:"name"`
+				Color   string `json:"color"`
+				Enabled *bool  `json:"enabled"`
+			} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(r).Decode(&fc); err != nil {
+		return "", nil, fmt.Errorf("failed to parse GeoJSON: %w", err)
+	}
+	if fc.Neato == nil || fc.Neato.Width == 0 || fc.Neato.Height == 0 {
+		return "", nil, fmt.Errorf("missing or invalid 'neato' member with the map size")
+	}
+	w, h := float64(fc.Neato.Width), float64(fc.Neato.Height)
+	normalize := func(c [2]float64) [2]float64 {
+		return [2]float64{c[0] / w, (h - c[1]) / h}
+	}
+	var boundaries []*neato.Boundary
+	for _, f := range fc.Features {
+		if f.Properties.Kind != "boundary" {
+			continue
+		}
+		b := neato.Boundary{
+			ID:      f.ID,
+			Name:    f.Properties.Name,
+			Color:   f.Properties.Color,
+			Enabled: f.Properties.Enabled == nil || *f.Properties.Enabled,
+		}
+		var coords [][2]float64
+		switch f.Geometry.Type {
+		case "LineString":
+			b.Type = neato.BoundaryTypePolyline
+			if err := json.Unmarshal(f.Geometry.Coordinates, &coords); err != nil {
+				return "", nil, fmt.Errorf("invalid coordinates for boundary '%s': %w", f.ID, err)
+			}
+		case "Polygon":
+			b.Type = neato.BoundaryTypePolygon
+			var rings [][][2]float64
+			if err := json.Unmarshal(f.Geometry.Coordinates, &rings); err != nil {
+				return "", nil, fmt.Errorf("invalid coordinates for boundary '%s': %w", f.ID, err)
+			}
+			// a boundary cannot have holes, and dropping them would
+			// upload an area larger than the one that was drawn
+			if len(rings) > 1 {
+				return "", nil, fmt.Errorf("boundary '%s' has %d holes, boundaries must be polygons without holes", f.ID, len(rings)-1)
+			}
+			if len(rings) > 0 {
+				coords = rings[0]
+				// GeoJSON rings repeat the first vertex, boundaries do not
+				if len(coords) > 1 && coords[0] == coords[len(coords)-1] {
+					coords = coords[:len(coords)-1]
+				}
+			}
+		default:
+			return "", nil, fmt.Errorf("unsupported geometry '%s' for boundary '%s'", f.Geometry.Type, f.ID)
+		}
+		for _, c := range coords {
+			b.Vertices = append(b.Vertices, normalize(c))
+		}
+		boundaries = append(boundaries, &b)
+	}
+	return fc.Neato.MapID, boundaries, nil
+}
@@ -0,0 +1,240 @@
+package maps
+
+import (
+	"bytes"
+	"image"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/insomniacslk/neato"
+)
+
+func testVectorMap() *VectorMap {
+	return &VectorMap{
+		MapID:   "map-1",
+		Width:   200,
+		Height:  100,
+		Cleaned: []image.Rectangle{image.Rect(10, 10, 50, 30)},
+		Boundaries: []*neato.Boundary{
+			{
+				ID:       "zone-1",
+				Type:     neato.BoundaryTypePolygon,
+				Name:     "Kitchen & hall",
+				Color:    "#00ff00",
+				Enabled:  true,
+				Vertices: [][2]float64{{0.1, 0.2}, {0.5, 0.2}, {0.5, 0.6}},
+			},
+			{
+				ID:       "wall-1",
+				Type:     neato.BoundaryTypePolyline,
+				Name:     "No go",
+				Vertices: [][2]float64{{0.25, 0.5}, {0.75, 0.5}},
+			},
+		},
+	}
+}
+
+func TestSVGRoundTrip(t *testing.T) {
+	v := testVectorMap()
+	var buf bytes.Buffer
+	if err := v.WriteSVG(&buf); err != nil {
+		t.Fatalf("WriteSVG failed: %v", err)
+	}
+	mapID, boundaries, err := ReadBoundariesSVG(&buf)
+	if err != nil {
+		t.Fatalf("ReadBoundariesSVG failed: %v", err)
+	}
+	if mapID != v.MapID {
+		t.Errorf("got map ID '%s', want '%s'", mapID, v.MapID)
+	}
+	if !reflect.DeepEqual(boundaries, v.Boundaries) {
+		t.Errorf("got boundaries %+v, want %+v", boundaries, v.Boundaries)
+	}
+}
+
+func TestGeoJSONRoundTrip(t *testing.T) {
+	v := testVectorMap()
+	var buf bytes.Buffer
+	if err := v.WriteGeoJSON(&buf); err != nil {
+		t.Fatalf("WriteGeoJSON failed: %v", err)
+	}
+	mapID, boundaries, err := ReadBoundariesGeoJSON(&buf)
+	if err != nil {
+		t.Fatalf("ReadBoundariesGeoJSON failed: %v", err)
+	}
+	if mapID != v.MapID {
+		t.Errorf("got map ID '%s', want '%s'", mapID, v.MapID)
+	}
+	if len(boundaries) != len(v.Boundaries) {
+		t.Fatalf("got %d boundaries, want %d", len(boundaries), len(v.Boundaries))
+	}
+	for i, b := range boundaries {
+		want := v.Boundaries[i]
+		if b.ID != want.ID || b.Type != want.Type || b.Name != want.Name || len(b.Vertices) != len(want.Vertices) {
+			t.Errorf("got boundary %v, want %v", b, want)
+		}
+	}
+}
+
+func TestReadBoundariesSVG(t *testing.T) {
+	const header = `<svg xmlns="http://www.w3.org/2000/svg" width="200" height="100" viewBox="0 0 200 100" data-map-id="map-1">`
+	for _, tt := range []struct {
+		name    string
+		svg     string
+		want    [][][2]float64
+		wantErr string
+	}{
+		{
+			name: "polygon",
+			svg:  header + `<polygon class="boundary" id="a" points="20,10 100,10 100,50"/></svg>`,
+			want: [][][2]float64{{{0.1, 0.1}, {0.5, 0.1}, {0.5, 0.5}}},
+		},
+		{
+			name: "view box",
+			svg:  `<svg xmlns="http://www.w3.org/2000/svg" width="100mm" height="50mm" viewBox="10 10 200 100"><polygon class="boundary" id="a" points="30,20 110,20 110,60"/></svg>`,
+			want: [][][2]float64{{{0.1, 0.1}, {0.5, 0.1}, {0.5, 0.5}}},
+		},
+		{
+			name: "group and editor metadata",
+			svg:  `<svg xmlns="http://www.w3.org/2000/svg" xmlns:sodipodi="http://sodipodi.sourceforge.net/DTD/sodipodi-0.dtd" width="200" height="100"><sodipodi:namedview id="view"/><defs><path id="clip" d="M0 0h1v1z"/></defs><g id="layer1"><polyline class="boundary" id="a" points="0,0 200,100"/></g></svg>`,
+			want: [][][2]float64{{{0, 0}, {1, 1}}},
+		},
+		{
+			name: "cleaned area",
+			svg:  header + `<path id="cleaned" d="M0 0h10v10h-10z"/></svg>`,
+		},
+		{
+			name:    "path",
+			svg:     header + `<path id="zone" d="M0 0h10v10h-10z"/></svg>`,
+			wantErr: "unsupported <path",
+		},
+		{
+			name:    "rect",
+			svg:     header + `<rect id="zone" x="0" y="0" width="10" height="10"/></svg>`,
+			wantErr: "unsupported <rect",
+		},
+		{
+			name:    "polygon without class",
+			svg:     header + `<polygon id="zone" points="0,0 10,0 10,10"/></svg>`,
+			wantErr: "unsupported <polygon",
+		},
+		{
+			name:    "transform on boundary",
+			svg:     header + `<polygon class="boundary" id="a" transform="translate(10,0)" points="0,0 10,0 10,10"/></svg>`,
+			wantErr: "unsupported transform",
+		},
+		{
+			name:    "transform on group",
+			svg:     header + `<g transform="scale(2)"><polygon class="boundary" id="a" points="0,0 10,0 10,10"/></g></svg>`,
+			wantErr: "unsupported transform",
+		},
+		{
+			name:    "invalid point",
+			svg:     header + `<polygon class="boundary" id="a" points="0,0 10"/></svg>`,
+			wantErr: "invalid point",
+		},
+		{
+			name:    "invalid view box",
+			svg:     `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200"></svg>`,
+			wantErr: "invalid SVG view box",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, boundaries, err := ReadBoundariesSVG(strings.NewReader(tt.svg))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing '%s'", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got [][][2]float64
+			for _, b := range boundaries {
+				got = append(got, b.Vertices)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got vertices %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadBoundariesGeoJSON(t *testing.T) {
+	const header = `{"type": "FeatureCollection", "neato": {"map_id": "map-1", "width": 200, "height": 100}, "features": [`
+	feature := func(geometry string) string {
+		return `{"type": "Feature", "id": "a", "properties": {"kind": "boundary"}, "geometry": ` + geometry + `}`
+	}
+	for _, tt := range []struct {
+		name    string
+		geojson string
+		want    [][][2]float64
+		wantErr string
+	}{
+		{
+			name:    "polygon",
+			geojson: header + feature(`{"type": "Polygon", "coordinates": [[[20, 90], [100, 90], [100, 50], [20, 90]]]}`) + `]}`,
+			want:    [][][2]float64{{{0.1, 0.1}, {0.5, 0.1}, {0.5, 0.5}}},
+		},
+		{
+			name:    "line",
+			geojson: header + feature(`{"type": "LineString", "coordinates": [[0, 100], [200, 0]]}`) + `]}`,
+			want:    [][][2]float64{{{0, 0}, {1, 1}}},
+		},
+		{
+			name:    "polygon with a hole",
+			geojson: header + feature(`{"type": "Polygon", "coordinates": [[[0, 0], [200, 0], [200, 100], [0, 0]], [[10, 10], [20, 10], [20, 20], [10, 10]]]}`) + `]}`,
+			wantErr: "has 1 holes",
+		},
+		{
+			name:    "point",
+			geojson: header + feature(`{"type": "Point", "coordinates": [0, 0]}`) + `]}`,
+			wantErr: "unsupported geometry",
+		},
+		{
+			name:    "missing size",
+			geojson: `{"type": "FeatureCollection", "features": []}`,
+			wantErr: "missing or invalid 'neato' member",
+		},
+	} {
+		_, boundaries, err := ReadBoundariesGeoJSON(strings.NewReader(tt.geojson))
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: got error %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var got [][][2]float64
+		for _, b := range boundaries {
+			got = append(got, b.Vertices)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewFloorPlanVectorMap(t *testing.T) {
+	// the floor plan is larger than the run image, and the run is drawn
+	// 10 pixels right and 5 down on it
+	floorPlan := testRunImage(image.Pt(60, 40), image.Rect(10, 5, 50, 35))
+	run := testRunImage(image.Pt(40, 30), image.Rect(0, 0, 40, 30))
+	boundaries := []*neato.Boundary{{ID: "a", Type: neato.BoundaryTypePolyline, Vertices: [][2]float64{{0, 0}, {1, 1}}}}
+
+	v := NewFloorPlanVectorMap("map-1", run, floorPlan, nil, 16, boundaries)
+	if v.Width != 60 || v.Height != 40 {
+		t.Errorf("got size %dx%d, want the floor plan size 60x40", v.Width, v.Height)
+	}
+	if want := []image.Rectangle{image.Rect(10, 5, 50, 35)}; !reflect.DeepEqual(v.Cleaned, want) {
+		t.Errorf("got cleaned area %v, want %v", v.Cleaned, want)
+	}
+	if x, y := v.toPixels(boundaries[0].Vertices[1]); x != 60 || y != 40 {
+		t.Errorf("boundary vertex drawn at (%g, %g), want (60, 40)", x, y)
+	}
+}
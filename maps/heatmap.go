@@ -0,0 +1,118 @@
+package maps
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+)
+
+// DefaultMaxOffset is the default maximum translation, in pixels, used to
+// align map images.
+const DefaultMaxOffset = 64
+
+// Heatmap accumulates the cleaned area of many runs over the same persistent
+// map. Every added image is aligned to the first one.
+type Heatmap struct {
+	Covered   CoveredFunc
+	MaxOffset int
+
+	ref   *Mask
+	masks []*Mask
+}
+
+func NewHeatmap() *Heatmap {
+	return &Heatmap{
+		Covered:   DefaultCovered,
+		MaxOffset: DefaultMaxOffset,
+	}
+}
+
+// Add aligns the map image to the previously added ones and accumulates its
+// cleaned area. It returns the offset applied to the image.
+func (h *Heatmap) Add(img image.Image) image.Point {
+	m := CoverageMask(img, h.Covered)
+	var off image.Point
+	if h.ref == nil {
+		h.ref = m
+	} else {
+		off = Align(h.ref, m, h.MaxOffset)
+	}
+	h.masks = append(h.masks, m.Translate(off))
+	return off
+}
+
+// Runs returns the number of added images.
+func (h *Heatmap) Runs() int {
+	return len(h.masks)
+}
+
+// Bounds returns the union of the bounds of all the aligned images.
+func (h *Heatmap) Bounds() image.Rectangle {
+	var r image.Rectangle
+	for _, m := range h.masks {
+		r = r.Union(m.Rect)
+	}
+	return r
+}
+
+// Counts returns, for every pixel within Bounds, how many runs cleaned it,
+// in row-major order.
+func (h *Heatmap) Counts() []int {
+	r := h.Bounds()
+	counts := make([]int, r.Dx()*r.Dy())
+	for _, m := range h.masks {
+		for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+			for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+				if m.At(x, y) {
+					counts[(y-r.Min.Y)*r.Dx()+(x-r.Min.X)]++
+				}
+			}
+		}
+	}
+	return counts
+}
+
+// Image renders the heatmap: pixels that were never cleaned are transparent,
+// the others go from blue (rarely cleaned) to red (cleaned in every run).
+func (h *Heatmap) Image() image.Image {
+	r := h.Bounds()
+	img := image.NewNRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	runs := h.Runs()
+	for i, c := range h.Counts() {
+		if c == 0 {
+			continue
+		}
+		img.SetNRGBA(i%r.Dx(), i/r.Dx(), heatColor(float64(c)/float64(runs)))
+	}
+	return img
+}
+
+// WritePNG encodes the heatmap image as PNG.
+func (h *Heatmap) WritePNG(w io.Writer) error {
+	return png.Encode(w, h.Image())
+}
+
+// heatColor maps a value between 0 and 1 to a blue-cyan-green-yellow-red
+// gradient.
+func heatColor(v float64) color.NRGBA {
+	stops := []color.NRGBA{
+		{0, 0, 255, 255},
+		{0, 255, 255, 255},
+		{0, 255, 0, 255},
+		{255, 255, 0, 255},
+		{255, 0, 0, 255},
+	}
+	if v <= 0 {
+		return stops[0]
+	}
+	if v >= 1 {
+		return stops[len(stops)-1]
+	}
+	pos := v * float64(len(stops)-1)
+	i := int(pos)
+	t := pos - float64(i)
+	a, b := stops[i], stops[i+1]
+	lerp := func(x, y uint8) uint8 { return uint8(float64(x) + (float64(y)-float64(x))*t) }
+	return color.NRGBA{lerp(a.R, b.R), lerp(a.G, b.G), lerp(a.B, b.B), 255}
+}
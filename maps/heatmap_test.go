@@ -0,0 +1,131 @@
+package maps
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// testRunImage returns a white map image of the given size where the given
+// rectangles are painted as cleaned.
+func testRunImage(size image.Point, cleaned ...image.Rectangle) image.Image {
+	img := image.NewNRGBA(image.Rectangle{Max: size})
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	for _, r := range cleaned {
+		draw.Draw(img, r, image.NewUniform(color.NRGBA{120, 120, 200, 255}), image.Point{}, draw.Src)
+	}
+	return img
+}
+
+func TestHeatmapEmpty(t *testing.T) {
+	h := NewHeatmap()
+	if h.Runs() != 0 || !h.Bounds().Empty() || len(h.Counts()) != 0 {
+		t.Errorf("got %d runs, bounds %v and %d counts, want an empty heatmap", h.Runs(), h.Bounds(), len(h.Counts()))
+	}
+	if !h.Image().Bounds().Empty() {
+		t.Errorf("got image bounds %v, want empty", h.Image().Bounds())
+	}
+}
+
+func TestHeatmapCounts(t *testing.T) {
+	size := image.Pt(8, 8)
+	for _, tc := range []struct {
+		name  string
+		runs  []image.Image
+		at    map[image.Point]int
+		total int
+	}{
+		{
+			name:  "single run",
+			runs:  []image.Image{testRunImage(size, image.Rect(0, 0, 4, 4))},
+			at:    map[image.Point]int{{0, 0}: 1, {3, 3}: 1, {4, 4}: 0},
+			total: 16,
+		},
+		{
+			name: "identical runs",
+			runs: []image.Image{
+				testRunImage(size, image.Rect(0, 0, 4, 4)),
+				testRunImage(size, image.Rect(0, 0, 4, 4)),
+				testRunImage(size, image.Rect(0, 0, 4, 4)),
+			},
+			at:    map[image.Point]int{{0, 0}: 3, {3, 3}: 3, {4, 4}: 0},
+			total: 48,
+		},
+		{
+			name: "overlapping runs",
+			runs: []image.Image{
+				testRunImage(size, image.Rect(0, 0, 4, 4)),
+				testRunImage(size, image.Rect(2, 2, 6, 6)),
+			},
+			at:    map[image.Point]int{{0, 0}: 1, {2, 2}: 2, {3, 3}: 2, {5, 5}: 1, {7, 7}: 0},
+			total: 32,
+		},
+	} {
+		h := NewHeatmap()
+		// the runs are not moved, so that the expected counts are easy to
+		// tell
+		h.MaxOffset = 0
+		for _, img := range tc.runs {
+			if off := h.Add(img); off != (image.Point{}) {
+				t.Errorf("%s: got offset %v, want none", tc.name, off)
+			}
+		}
+		if h.Runs() != len(tc.runs) {
+			t.Errorf("%s: got %d runs, want %d", tc.name, h.Runs(), len(tc.runs))
+		}
+		if h.Bounds() != (image.Rectangle{Max: size}) {
+			t.Errorf("%s: got bounds %v, want %v", tc.name, h.Bounds(), image.Rectangle{Max: size})
+		}
+		counts := h.Counts()
+		total := 0
+		for _, c := range counts {
+			total += c
+		}
+		if total != tc.total {
+			t.Errorf("%s: got %d cleaned pixels over all runs, want %d", tc.name, total, tc.total)
+		}
+		for p, want := range tc.at {
+			if got := counts[p.Y*size.X+p.X]; got != want {
+				t.Errorf("%s: %v cleaned %d times, want %d", tc.name, p, got, want)
+			}
+		}
+	}
+}
+
+func TestHeatmapAlign(t *testing.T) {
+	size := image.Pt(40, 40)
+	h := NewHeatmap()
+	h.MaxOffset = 8
+	h.Add(testRunImage(size, image.Rect(8, 8, 24, 20)))
+	// the same run, moved 4 pixels right and 2 down
+	if off := h.Add(testRunImage(size, image.Rect(12, 10, 28, 22))); off != image.Pt(-4, -2) {
+		t.Fatalf("got offset %v, want (-4,-2)", off)
+	}
+	b := h.Bounds()
+	counts := h.Counts()
+	at := func(x, y int) int { return counts[(y-b.Min.Y)*b.Dx()+(x-b.Min.X)] }
+	if got := at(8, 8); got != 2 {
+		t.Errorf("aligned corner cleaned %d times, want 2", got)
+	}
+	if got := at(23, 19); got != 2 {
+		t.Errorf("aligned corner cleaned %d times, want 2", got)
+	}
+}
+
+func TestHeatColor(t *testing.T) {
+	for _, tc := range []struct {
+		v    float64
+		want color.NRGBA
+	}{
+		{-1, color.NRGBA{0, 0, 255, 255}},
+		{0, color.NRGBA{0, 0, 255, 255}},
+		{0.5, color.NRGBA{0, 255, 0, 255}},
+		{1, color.NRGBA{255, 0, 0, 255}},
+		{2, color.NRGBA{255, 0, 0, 255}},
+	} {
+		if got := heatColor(tc.v); got != tc.want {
+			t.Errorf("heatColor(%g) = %v, want %v", tc.v, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package maps analyzes the cleaning map images of Neato robots.
+package maps
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/insomniacslk/neato"
+)
+
+// CoveredFunc reports whether a pixel of a map image is part of the cleaned
+// area.
+type CoveredFunc func(c color.Color) bool
+
+// DefaultCovered treats as cleaned every opaque pixel that is neither close
+// to white (background) nor close to black (walls and obstacles).
+func DefaultCovered(c color.Color) bool {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	if n.A < 128 {
+		return false
+	}
+	lum := (299*int(n.R) + 587*int(n.G) + 114*int(n.B)) / 1000
+	return lum > 48 && lum < 230
+}
+
+// Mask is a bitmap of the cleaned area of a map image.
+type Mask struct {
+	Rect image.Rectangle
+	bits []bool
+}
+
+func NewMask(r image.Rectangle) *Mask {
+	return &Mask{Rect: r, bits: make([]bool, r.Dx()*r.Dy())}
+}
+
+// CoverageMask returns the mask of the pixels of img for which covered
+// returns true. If covered is nil, DefaultCovered is used.
+func CoverageMask(img image.Image, covered CoveredFunc) *Mask {
+	if covered == nil {
+		covered = DefaultCovered
+	}
+	b := img.Bounds()
+	m := NewMask(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			if covered(img.At(b.Min.X+x, b.Min.Y+y)) {
+				m.bits[y*b.Dx()+x] = true
+			}
+		}
+	}
+	return m
+}
+
+// At reports whether the point (x, y) is covered. Points outside of the mask
+// are not covered.
+func (m *Mask) At(x, y int) bool {
+	if !(image.Point{x, y}).In(m.Rect) {
+		return false
+	}
+	return m.bits[(y-m.Rect.Min.Y)*m.Rect.Dx()+(x-m.Rect.Min.X)]
+}
+
+func (m *Mask) Set(x, y int, v bool) {
+	if !(image.Point{x, y}).In(m.Rect) {
+		return
+	}
+	m.bits[(y-m.Rect.Min.Y)*m.Rect.Dx()+(x-m.Rect.Min.X)] = v
+}
+
+// Area returns the number of covered pixels.
+func (m *Mask) Area() int {
+	n := 0
+	for _, b := range m.bits {
+		if b {
+			n++
+		}
+	}
+	return n
+}
+
+// Translate returns a copy of the mask moved by off.
+func (m *Mask) Translate(off image.Point) *Mask {
+	return &Mask{Rect: m.Rect.Add(off), bits: append([]bool(nil), m.bits...)}
+}
+
+// downsample returns a mask that is factor times smaller, where a pixel is
+// covered if any of the source pixels is.
+func (m *Mask) downsample(factor int) *Mask {
+	r := image.Rect(
+		floorDiv(m.Rect.Min.X, factor), floorDiv(m.Rect.Min.Y, factor),
+		floorDiv(m.Rect.Max.X-1, factor)+1, floorDiv(m.Rect.Max.Y-1, factor)+1,
+	)
+	d := NewMask(r)
+	for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+		for x := m.Rect.Min.X; x < m.Rect.Max.X; x++ {
+			if m.At(x, y) {
+				d.Set(floorDiv(x, factor), floorDiv(y, factor), true)
+			}
+		}
+	}
+	return d
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// GroupByPersistentMap groups maps by their persistent map ID. Maps that
+// were not cleaned over a persistent map are not returned, since there is
+// nothing to align them to.
+func GroupByPersistentMap(ms []*neato.Map) map[string][]*neato.Map {
+	groups := make(map[string][]*neato.Map)
+	for _, m := range ms {
+		if m.PersistentMapID == nil || *m.PersistentMapID == "" {
+			continue
+		}
+		groups[*m.PersistentMapID] = append(groups[*m.PersistentMapID], m)
+	}
+	return groups
+}
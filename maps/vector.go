@@ -0,0 +1,52 @@
+package maps
+
+import (
+	"image"
+	"sort"
+)
+
+// Rectangles decomposes the covered area of the mask into rectangles:
+// horizontal runs of covered pixels are merged with identical runs of the
+// rows below them. The union of the returned rectangles is exactly the
+// covered area, which makes it suitable for vector formats.
+func (m *Mask) Rectangles() []image.Rectangle {
+	type run struct{ x0, x1 int }
+	var done []image.Rectangle
+	// open rectangles, keyed by the run they extend
+	open := make(map[run]image.Rectangle)
+	for y := m.Rect.Min.Y; y < m.Rect.Max.Y; y++ {
+		next := make(map[run]image.Rectangle)
+		for x := m.Rect.Min.X; x < m.Rect.Max.X; {
+			if !m.At(x, y) {
+				x++
+				continue
+			}
+			x0 := x
+			for x < m.Rect.Max.X && m.At(x, y) {
+				x++
+			}
+			r := run{x0, x}
+			if rect, ok := open[r]; ok {
+				rect.Max.Y = y + 1
+				next[r] = rect
+				delete(open, r)
+			} else {
+				next[r] = image.Rect(x0, y, x, y+1)
+			}
+		}
+		for _, rect := range open {
+			done = append(done, rect)
+		}
+		open = next
+	}
+	for _, rect := range open {
+		done = append(done, rect)
+	}
+	sort.Slice(done, func(i, j int) bool {
+		if done[i].Min.Y != done[j].Min.Y {
+			return done[i].Min.Y < done[j].Min.Y
+		}
+		return done[i].Min.X < done[j].Min.X
+	})
+	return done
+}
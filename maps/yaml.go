@@ -0,0 +1,37 @@
+package maps
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/insomniacslk/neato"
+	"gopkg.in/yaml.v3"
+)
+
+// boundariesFile is the YAML representation of the boundaries of a
+// persistent map.
+type boundariesFile struct {
+	MapID      string            `yaml:"map_id"`
+	Boundaries []*neato.Boundary `yaml:"boundaries"`
+}
+
+// WriteYAML writes the boundaries of the map as YAML, which is the easiest
+// format to edit by hand. The cleaned area is not included.
+func (v *VectorMap) WriteYAML(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&boundariesFile{MapID: v.MapID, Boundaries: v.Boundaries}); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// ReadBoundariesYAML reads the persistent map ID and the boundaries written
+// by WriteYAML.
+func ReadBoundariesYAML(r io.Reader) (string, []*neato.Boundary, error) {
+	var f boundariesFile
+	if err := yaml.NewDecoder(r).Decode(&f); err != nil {
+		return "", nil, fmt.Errorf("failed to parse YAML: %w", err)
+	}
+	return f.MapID, f.Boundaries, nil
+}
@@ -0,0 +1,52 @@
+package neato
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeServerMapStats(t *testing.T) {
+	for _, tc := range []struct {
+		raw     string
+		want    ServerMapStats
+		wantErr bool
+	}{
+		{``, nil, false},
+		{`null`, nil, false},
+		{`{}`, nil, false},
+		{`{"note": "text only"}`, nil, false},
+		{`{"a": 12, "b": 340.5, "c": "x", "d": {"e": 1}}`, ServerMapStats{"a": 12, "b": 340.5}, false},
+		{`[1, 2]`, nil, true},
+		{`"stats"`, nil, true},
+	} {
+		got, err := DecodeServerMapStats(json.RawMessage(tc.raw))
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%q: got error %v, want error: %v", tc.raw, err, tc.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: got %v, want %v", tc.raw, got, tc.want)
+		}
+	}
+}
+
+func TestMapHistoryServerStats(t *testing.T) {
+	f := newFakeCloud(t)
+	robots, err := f.account(nil).Robots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.setMapStats(map[string]interface{}{"total": 99})
+	maps, stats, err := robots[0].MapHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the server values must not replace the totals of the map list
+	if stats.Runs != len(maps) || stats.CleanedArea != 12.5 {
+		t.Errorf("got %d runs and %g sqm, want the totals of the map list", stats.Runs, stats.CleanedArea)
+	}
+	if !reflect.DeepEqual(stats.Server, ServerMapStats{"total": 99}) {
+		t.Errorf("got server stats %v", stats.Server)
+	}
+}
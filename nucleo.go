@@ -0,0 +1,79 @@
+package neato
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const nucleoAuthPrefix = "NEATOAPP "
+
+// NucleoMaxClockSkew is how far the Date header of a signed Nucleo request
+// may drift from the local clock before VerifyNucleoRequest rejects it.
+var NucleoMaxClockSkew = 5 * time.Minute
+
+var (
+	ErrInvalidSignature = errors.New("invalid Nucleo signature")
+	ErrStaleDate        = errors.New("request date outside of the allowed Nucleo clock skew")
+)
+
+// nucleoSignature computes the HMAC-SHA256 signature used in the
+// `Authorization: NEATOAPP <signature>` header of Nucleo requests.
+func nucleoSignature(serial, secretKey, date string, body []byte) string {
+	msg := fmt.Sprintf("%s\n%s\n%s", strings.ToLower(serial), date, body)
+	h := hmac.New(sha256.New, []byte(secretKey))
+	h.Write([]byte(msg))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyNucleoRequest is the inverse of Robot.Header: it checks that req
+// carries a valid NEATOAPP signature for the given robot serial and secret
+// key, and that its Date header is within NucleoMaxClockSkew of now. The
+// request body is consumed and replaced, so it can still be read afterwards.
+func VerifyNucleoRequest(serial, secretKey string, req *http.Request) error {
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, nucleoAuthPrefix) {
+		return fmt.Errorf("%w: missing NEATOAPP authorization header", ErrInvalidSignature)
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(auth, nucleoAuthPrefix))
+	if err != nil {
+		return fmt.Errorf("%w: signature is not hex-encoded: %v", ErrInvalidSignature, err)
+	}
+
+	dateStr := req.Header.Get("Date")
+	if dateStr == "" {
+		return fmt.Errorf("%w: missing Date header", ErrStaleDate)
+	}
+	date, err := http.ParseTime(dateStr)
+	if err != nil {
+		return fmt.Errorf("%w: cannot parse Date header '%s': %v", ErrStaleDate, dateStr, err)
+	}
+	if skew := time.Since(date); skew > NucleoMaxClockSkew || skew < -NucleoMaxClockSkew {
+		return fmt.Errorf("%w: date '%s' is %s away from local time", ErrStaleDate, dateStr, skew.Round(time.Second))
+	}
+
+	var body []byte
+	if req.Body != nil {
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read request body: %w", err)
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	want, err := hex.DecodeString(nucleoSignature(serial, secretKey, dateStr, body))
+	if err != nil {
+		return fmt.Errorf("failed to compute signature: %w", err)
+	}
+	if !hmac.Equal(got, want) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package neato
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestVerifyNucleoRequest(t *testing.T) {
+	const serial, secretKey = "serial-1", "secret"
+	body := []byte(`{"reqId":"1","cmd":"getRobotState"}`)
+	robot := &Robot{Serial: serial, SecretKey: secretKey}
+
+	// signed returns a request signed by the robot, modified by edit.
+	signed := func(edit func(req *http.Request)) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/vendors/neato/robots/"+serial+"/messages", bytes.NewReader(body))
+		for k, v := range *robot.Header(body) {
+			req.Header[k] = v
+		}
+		if edit != nil {
+			edit(req)
+		}
+		return req
+	}
+	dated := func(d time.Time) func(*http.Request) {
+		return func(req *http.Request) {
+			date := d.UTC().Format(http.TimeFormat)
+			req.Header.Set("Date", date)
+			req.Header.Set("Authorization", nucleoAuthPrefix+nucleoSignature(serial, secretKey, date, body))
+		}
+	}
+
+	for _, tc := range []struct {
+		name      string
+		serial    string
+		secretKey string
+		req       *http.Request
+		wantErr   error
+	}{
+		{"valid", serial, secretKey, signed(nil), nil},
+		{"serial is case-insensitive", "SERIAL-1", secretKey, signed(nil), nil},
+		{"wrong secret key", serial, "other", signed(nil), ErrInvalidSignature},
+		{"wrong serial", "serial-2", secretKey, signed(nil), ErrInvalidSignature},
+		{"tampered body", serial, secretKey, signed(func(req *http.Request) {
+			req.Body = io.NopCloser(bytes.NewReader([]byte(`{"reqId":"1","cmd":"startCleaning"}`)))
+		}), ErrInvalidSignature},
+		{"missing authorization", serial, secretKey, signed(func(req *http.Request) {
+			req.Header.Del("Authorization")
+		}), ErrInvalidSignature},
+		{"other authorization scheme", serial, secretKey, signed(func(req *http.Request) {
+			req.Header.Set("Authorization", "Token token=abc")
+		}), ErrInvalidSignature},
+		{"signature is not hex", serial, secretKey, signed(func(req *http.Request) {
+			req.Header.Set("Authorization", nucleoAuthPrefix+"not-hex")
+		}), ErrInvalidSignature},
+		{"missing date", serial, secretKey, signed(func(req *http.Request) {
+			req.Header.Del("Date")
+		}), ErrStaleDate},
+		{"invalid date", serial, secretKey, signed(func(req *http.Request) {
+			req.Header.Set("Date", "yesterday")
+		}), ErrStaleDate},
+		{"date too old", serial, secretKey, signed(dated(time.Now().Add(-NucleoMaxClockSkew - time.Minute))), ErrStaleDate},
+		{"date too far in the future", serial, secretKey, signed(dated(time.Now().Add(NucleoMaxClockSkew + time.Minute))), ErrStaleDate},
+		{"date within skew", serial, secretKey, signed(dated(time.Now().Add(-NucleoMaxClockSkew / 2))), nil},
+		{"date changed after signing", serial, secretKey, signed(func(req *http.Request) {
+			req.Header.Set("Date", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+		}), ErrInvalidSignature},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := VerifyNucleoRequest(tc.serial, tc.secretKey, tc.req)
+			if tc.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			} else if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyNucleoRequestKeepsBody(t *testing.T) {
+	body := []byte(`{"reqId":"1","cmd":"getRobotState"}`)
+	robot := &Robot{Serial: "serial", SecretKey: "secret"}
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	for k, v := range *robot.Header(body) {
+		req.Header[k] = v
+	}
+	if err := VerifyNucleoRequest(robot.Serial, robot.SecretKey, req); err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Errorf("got body %q after verification, want %q", got, body)
+	}
+}
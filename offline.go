@@ -0,0 +1,142 @@
+package neato
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrRobotOffline is returned by robot commands when Nucleo reports that the
+// robot is not online, when the request times out, or when the circuit
+// breaker of the robot is open.
+var ErrRobotOffline = errors.New("robot is offline")
+
+// OfflineCoolDown is how long commands to a robot fail fast with
+// ErrRobotOffline after it was found offline.
+var OfflineCoolDown = 30 * time.Second
+
+// isOffline reports whether err, returned by a request made with ctx, means
+// that the robot is not reachable. Timeouts caused by ctx itself do not
+// count, since the caller gave up before the robot could answer.
+func isOffline(ctx context.Context, err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusNotFound || bytes.Contains(statusErr.Body, []byte("Robot not online"))
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// circuitBreaker stops sending commands to a robot for OfflineCoolDown after
+// it was found offline. Once the cool-down is over, a single command is let
+// through to probe the robot, and the others keep failing fast until it
+// completes. The zero value is a closed breaker.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	openUntil time.Time
+	probing   bool
+	lastErr   error
+}
+
+// allow returns an error if commands must fail fast, otherwise whether the
+// command about to be sent is the probe of a half-open breaker.
+func (b *circuitBreaker) allow() (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openUntil.IsZero() {
+		return false, nil
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false, b.lastErr
+	}
+	b.probing = true
+	return true, nil
+}
+
+// record updates the breaker with the outcome of a command. offline reports
+// whether err means the robot is not reachable.
+func (b *circuitBreaker) record(err error, offline, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	if offline {
+		b.openUntil = time.Now().Add(OfflineCoolDown)
+		b.lastErr = err
+		return
+	}
+	b.openUntil = time.Time{}
+	b.lastErr = nil
+}
+
+// abort releases the probe without changing the state of the breaker.
+func (b *circuitBreaker) abort(probe bool) {
+	if !probe {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// open reports whether the breaker is failing commands fast.
+func (b *circuitBreaker) open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.openUntil.IsZero() && (time.Now().Before(b.openUntil) || b.probing)
+}
+
+// fastFailError marks the errors of commands that the circuit breaker failed
+// without sending them.
+type fastFailError struct {
+	err error
+}
+
+func (e *fastFailError) Error() string { return e.err.Error() }
+func (e *fastFailError) Unwrap() error { return e.err }
+
+// guard runs fn through the circuit breaker of the robot, turning offline
+// errors into ErrRobotOffline.
+func (r *Robot) guard(ctx context.Context, fn func() error) error {
+	probe, lastErr := r.breaker.allow()
+	if lastErr != nil {
+		return &fastFailError{fmt.Errorf("robot '%s': %w (last error: %v)", r.Serial, ErrRobotOffline, lastErr)}
+	}
+	err := fn()
+	if err != nil && ctx.Err() != nil {
+		// the caller gave up, which says nothing about the robot
+		r.breaker.abort(probe)
+		return err
+	}
+	offline := err != nil && isOffline(ctx, err)
+	r.breaker.record(err, offline, probe)
+	if offline {
+		return fmt.Errorf("robot '%s': %w: %v", r.Serial, ErrRobotOffline, err)
+	}
+	return err
+}
+
+// Online reports whether the robot answers to Nucleo commands. It returns
+// false without contacting the robot while its circuit breaker is open.
+// Errors other than ErrRobotOffline are returned as-is.
+func (r *Robot) Online(ctx context.Context) (bool, error) {
+	if r.breaker.open() {
+		return false, nil
+	}
+	if _, err := r.State(ctx); err != nil {
+		if errors.Is(err, ErrRobotOffline) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
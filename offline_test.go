@@ -0,0 +1,185 @@
+package neato
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	errOffline := errors.New("robot not online")
+	type step struct {
+		// op is one of allow, record, abort, expire and open
+		op string
+		// for record
+		err     error
+		offline bool
+		// probe is the argument of record and abort, and the expected
+		// result of allow
+		probe bool
+		// wantErr is the expected error of allow, wantOpen the expected
+		// result of open
+		wantErr  error
+		wantOpen bool
+	}
+	for _, tc := range []struct {
+		name  string
+		steps []step
+	}{
+		{"closed", []step{
+			{op: "open", wantOpen: false},
+			{op: "allow"},
+			{op: "record", err: errors.New("other error")},
+			{op: "allow"},
+		}},
+		{"opens when offline", []step{
+			{op: "allow"},
+			{op: "record", err: errOffline, offline: true},
+			{op: "open", wantOpen: true},
+			{op: "allow", wantErr: errOffline},
+		}},
+		{"half-open lets a single probe through", []step{
+			{op: "record", err: errOffline, offline: true},
+			{op: "expire"},
+			{op: "open", wantOpen: false},
+			{op: "allow", probe: true},
+			{op: "open", wantOpen: true},
+			{op: "allow", wantErr: errOffline},
+		}},
+		{"successful probe closes", []step{
+			{op: "record", err: errOffline, offline: true},
+			{op: "expire"},
+			{op: "allow", probe: true},
+			{op: "record", probe: true},
+			{op: "open", wantOpen: false},
+			{op: "allow"},
+		}},
+		{"failed probe opens again", []step{
+			{op: "record", err: errOffline, offline: true},
+			{op: "expire"},
+			{op: "allow", probe: true},
+			{op: "record", err: errOffline, offline: true, probe: true},
+			{op: "open", wantOpen: true},
+			{op: "allow", wantErr: errOffline},
+		}},
+		{"aborted probe releases the probe", []step{
+			{op: "record", err: errOffline, offline: true},
+			{op: "expire"},
+			{op: "allow", probe: true},
+			{op: "abort", probe: true},
+			{op: "allow", probe: true},
+		}},
+		{"abort without probe does nothing", []step{
+			{op: "record", err: errOffline, offline: true},
+			{op: "expire"},
+			{op: "allow", probe: true},
+			{op: "abort", probe: false},
+			{op: "allow", wantErr: errOffline},
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var b circuitBreaker
+			for i, s := range tc.steps {
+				switch s.op {
+				case "allow":
+					probe, err := b.allow()
+					if probe != s.probe || err != s.wantErr {
+						t.Fatalf("step %d: allow() = (%v, %v), want (%v, %v)", i, probe, err, s.probe, s.wantErr)
+					}
+				case "record":
+					b.record(s.err, s.offline, s.probe)
+				case "abort":
+					b.abort(s.probe)
+				case "expire":
+					b.mu.Lock()
+					b.openUntil = time.Now().Add(-time.Second)
+					b.mu.Unlock()
+				case "open":
+					if got := b.open(); got != s.wantOpen {
+						t.Fatalf("step %d: open() = %v, want %v", i, got, s.wantOpen)
+					}
+				default:
+					t.Fatalf("step %d: unknown op '%s'", i, s.op)
+				}
+			}
+		})
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+func TestIsOffline(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, tc := range []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"not found", context.Background(), &httpStatusError{StatusCode: http.StatusNotFound}, true},
+		{"robot not online", context.Background(), &httpStatusError{StatusCode: http.StatusBadRequest, Body: []byte(`{"message":"Robot not online"}`)}, true},
+		{"server error", context.Background(), &httpStatusError{StatusCode: http.StatusInternalServerError}, false},
+		{"timeout", context.Background(), fmt.Errorf("wrapped: %w", timeoutError{}), true},
+		{"timeout of the caller", canceled, timeoutError{}, false},
+		{"other", context.Background(), errors.New("other"), false},
+	} {
+		if got := isOffline(tc.ctx, tc.err); got != tc.want {
+			t.Errorf("%s: isOffline() = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRobotOffline(t *testing.T) {
+	f := newFakeCloud(t)
+	robots, err := f.account(nil).Robots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := robots[0]
+	ctx := context.Background()
+	var fastFails []bool
+	APICallHook = func(call APICall) {
+		fastFails = append(fastFails, call.FastFail)
+	}
+	t.Cleanup(func() { APICallHook = nil })
+
+	f.setOffline(true)
+	if _, err := r.State(ctx); !errors.Is(err, ErrRobotOffline) {
+		t.Fatalf("got error %v, want %v", err, ErrRobotOffline)
+	}
+	// while the breaker is open, nothing is sent to the robot
+	calls := atomic.LoadInt32(&f.nucleoCalls)
+	if _, err := r.State(ctx); !errors.Is(err, ErrRobotOffline) {
+		t.Fatalf("got error %v, want %v", err, ErrRobotOffline)
+	}
+	if online, err := r.Online(ctx); online || err != nil {
+		t.Errorf("Online() = (%v, %v), want (false, nil)", online, err)
+	}
+	if got := atomic.LoadInt32(&f.nucleoCalls); got != calls {
+		t.Errorf("%d requests sent while the breaker is open", got-calls)
+	}
+	if len(fastFails) != 2 || fastFails[0] || !fastFails[1] {
+		t.Errorf("got fast fails %v, want only the second request to fail fast", fastFails)
+	}
+
+	// once the cool-down is over, the robot is probed again
+	f.setOffline(false)
+	r.breaker.mu.Lock()
+	r.breaker.openUntil = time.Now().Add(-time.Second)
+	r.breaker.mu.Unlock()
+	if online, err := r.Online(ctx); !online || err != nil {
+		t.Errorf("Online() = (%v, %v), want (true, nil)", online, err)
+	}
+}
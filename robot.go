@@ -1,21 +1,22 @@
 package neato
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
-	"strconv"
-	"strings"
 	"time"
 )
 
+// Robot is safe for concurrent use by multiple goroutines. Its exported
+// fields are read-only once the robot has been returned by Account.Robots,
+// since the same robot is shared by every caller until the cache expires.
 type Robot struct {
 	session *PasswordSession
-	maps    []*Map
+	cache   *Cache
+	breaker circuitBreaker
 
 	Serial                            string   `json:"serial"`
 	Prefix                            *string  `json:"prefix"`
@@ -49,38 +50,75 @@ func (r *Robot) Header(body []byte) *url.Values {
 	header.Set("Accept", "application/vnd.neato.nucleo.v1")
 
 	// RFC2616-formatted date and time
-	date := time.Now().In(time.UTC).Format("Tue, 02 Jan 2006 15:04:05 GMT")
+	date := time.Now().In(time.UTC).Format(http.TimeFormat)
 	header.Set("Date", date)
 
-	msg := fmt.Sprintf("%s\n%s\n%s", strings.ToLower(r.Serial), date, body)
-	h := hmac.New(sha256.New, []byte(r.SecretKey))
-	h.Write([]byte(msg))
-	signature := hex.EncodeToString(h.Sum(nil))
-	header.Set("Authorization", "NEATOAPP "+signature)
+	header.Set("Authorization", nucleoAuthPrefix+nucleoSignature(r.Serial, r.SecretKey, date, body))
 
 	return &header
 }
 
-func (r *Robot) RefreshMaps() ([]*Map, error) {
-	r.maps = nil
-	return r.Maps()
+// RefreshMaps drops the cached maps of the robot and fetches them again.
+func (r *Robot) RefreshMaps(ctx context.Context) ([]*Map, error) {
+	r.cache.invalidate(cacheKeyMaps(r.Serial))
+	maps, _, err := r.MapHistoryContext(ctx)
+	return maps, err
 }
 
 func (r *Robot) Maps() ([]*Map, error) {
-	if r.maps != nil {
-		return r.maps, nil
+	maps, _, err := r.MapHistory()
+	return maps, err
+}
+
+// InvalidateCache drops the cached maps and state of the robot.
+func (r *Robot) InvalidateCache() {
+	r.cache.invalidate(cacheKeyMaps(r.Serial), cacheKeyState(r.Serial))
+}
+
+// mapHistory is the response of the maps endpoint.
+type mapHistory struct {
+	Stats json.RawMessage `json:"stats"`
+	Maps  []*Map          `json:"maps"`
+
+	stats *MapStats
+}
+
+// MapHistory returns the cleaning maps of the robot together with the
+// statistics computed over them and the values reported by the server, see
+// MapStats.Server. The result is cached for CacheConfig.MapsTTL.
+func (r *Robot) MapHistory() ([]*Map, *MapStats, error) {
+	return r.MapHistoryContext(context.Background())
+}
+
+// MapHistoryContext is like MapHistory, but gives up waiting for the maps when
+// ctx is done.
+func (r *Robot) MapHistoryContext(ctx context.Context) ([]*Map, *MapStats, error) {
+	fetch := func(ctx context.Context) (*mapHistory, error) {
+		var resp mapHistory
+		if err := r.session.get(ctx, "users/me/robots/"+r.Serial+"/maps", &resp); err != nil {
+			return nil, fmt.Errorf("failed to get maps: %w", err)
+		}
+		return &resp, nil
 	}
-	type mapsResponse struct {
-		// TODO figure out the stats field
-		Stats interface{}
-		Maps  []*Map
+	prepare := func(h *mapHistory, fetchedAt time.Time) {
+		for _, m := range h.Maps {
+			m.robot = r
+			m.mu.Lock()
+			m.fetchedAt = fetchedAt
+			m.mu.Unlock()
+		}
+		h.stats = NewMapStats(h.Maps)
+		h.stats.Raw = h.Stats
+		// a stats object of an unexpected shape is still available as Raw
+		if server, err := DecodeServerMapStats(h.Stats); err == nil {
+			h.stats.Server = server
+		}
 	}
-	var resp mapsResponse
-	if err := r.session.get("users/me/robots/"+r.Serial+"/maps", &resp); err != nil {
-		return nil, fmt.Errorf("failed to get maps: %w", err)
+	h, err := cached(ctx, r.cache, cacheKeyMaps(r.Serial), r.cache.config().MapsTTL, fetch, prepare)
+	if err != nil {
+		return nil, nil, err
 	}
-	r.maps = resp.Maps
-	return resp.Maps, nil
+	return h.Maps, h.stats, nil
 }
 
 type Result string
@@ -161,7 +199,7 @@ func (a Action) String() string {
 	case ActionSuspendedExploration:
 		return "suspended exploration"
 	default:
-		return "unknown"
+		return unknownEnum(int(a))
 	}
 }
 
@@ -178,7 +216,7 @@ func (s State) String() string {
 	case StateError:
 		return "error"
 	default:
-		return "unknown"
+		return unknownEnum(int(s))
 	}
 }
 
@@ -189,8 +227,8 @@ type RobotState struct {
 	Data     interface{} `json:"data"`
 	State    State       `json:"state"`
 	Action   Action      `json:"action"`
-	Error    *string     `json:"error"`
-	Alert    *string     `json:"alert"`
+	Error    *ErrorCode  `json:"error"`
+	Alert    *AlertCode  `json:"alert"`
 	Cleaning struct {
 		Category       Category       `json:"category"`
 		Mode           CleaningMode   `json:"mode"`
@@ -213,49 +251,54 @@ type RobotState struct {
 		Resume   bool `json:"resume"`
 		GoToBase bool `json:"goToBase"`
 	} `json:"availableCommands"`
-	AvailableServices struct {
-		FindMe         string `json:"findMe"`
-		GeneralInfo    string `json:"generalInfo"`
-		HouseCleaning  string `json:"houseCleaning"`
-		LocalStats     string `json:"localStats"`
-		ManualCleaning string `json:"manualCleaning"`
-		Maps           string `json:"maps"`
-		Preferences    string `json:"preferences"`
-		Schedule       string `json:"schedule"`
-		SpotCleaning   string `json:"spotCleaning"`
-		IECTest        string `json:"IECTest"`
-		LogCopy        string `json:"logCopy"`
-		SoftwareUpdate string `json:"softwareUpdate"`
-		Wifi           string `json:"wifi"`
-	} `json:"availableServices"`
-	Meta struct {
+	AvailableServices AvailableServices `json:"availableServices"`
+	Meta              struct {
 		ModelName string `json:"modelName"`
 		Firmware  string `json:"firmware"`
 	} `json:"meta"`
 }
 
+type AvailableServices struct {
+	FindMe         string `json:"findMe"`
+	GeneralInfo    string `json:"generalInfo"`
+	HouseCleaning  string `json:"houseCleaning"`
+	LocalStats     string `json:"localStats"`
+	ManualCleaning string `json:"manualCleaning"`
+	Maps           string `json:"maps"`
+	Preferences    string `json:"preferences"`
+	Schedule       string `json:"schedule"`
+	SpotCleaning   string `json:"spotCleaning"`
+	IECTest        string `json:"IECTest"`
+	LogCopy        string `json:"logCopy"`
+	SoftwareUpdate string `json:"softwareUpdate"`
+	Wifi           string `json:"wifi"`
+}
+
 func (s *RobotState) String() string {
 	errStr := "<not set>"
-	if s.Error != nil {
-		errStr = *s.Error
+	if s.Error != nil && *s.Error != "" {
+		errStr = s.Error.String()
 	}
 	alert := "<not set>"
-	if s.Alert != nil {
-		alert = *s.Alert
+	if s.Alert != nil && s.Alert.IsSet() {
+		alert = s.Alert.String()
 	}
 	return fmt.Sprintf("State: %s, Action: %s, Error: %s, Alert: %s, IsDocked: %v, Charge: %d", s.State, s.Action, errStr, alert, s.Details.IsDocked, s.Details.Charge)
 }
 
-func (r *Robot) State() (*RobotState, error) {
-	var resp RobotState
-	dataMap := map[string]interface{}{
-		"reqId": "1",
-		"cmd":   "getRobotState",
-	}
-	if err := r.post(dataMap, &resp); err != nil {
-		return nil, err
+func (r *Robot) State(ctx context.Context) (*RobotState, error) {
+	fetch := func(ctx context.Context) (*RobotState, error) {
+		resp, err := r.Do(ctx, &GetRobotStateCommand{})
+		if err != nil {
+			return nil, err
+		}
+		var state RobotState
+		if err := resp.Decode(&state); err != nil {
+			return nil, err
+		}
+		return &state, nil
 	}
-	return &resp, nil
+	return cached(ctx, r.cache, cacheKeyState(r.Serial), r.cache.config().StateTTL, fetch, nil)
 }
 
 type CleaningMode int
@@ -272,7 +315,7 @@ func (c CleaningMode) String() string {
 	case CleaningModeTurbo:
 		return "turbo"
 	default:
-		return "unknown"
+		return unknownEnum(int(c))
 	}
 }
 
@@ -293,25 +336,31 @@ func (n NavigationMode) String() string {
 	case NavigationModeDeep:
 		return "deep"
 	default:
-		return "unknown"
+		return unknownEnum(int(n))
 	}
 }
 
 type Category int
 
 var (
+	CategoryManual           Category = 1
 	CategoryNonPersistentMap Category = 2
+	CategorySpot             Category = 3
 	CategoryPersistentMap    Category = 4
 )
 
 func (c Category) String() string {
 	switch c {
+	case CategoryManual:
+		return "manual"
 	case CategoryNonPersistentMap:
 		return "non-persistent map"
+	case CategorySpot:
+		return "spot"
 	case CategoryPersistentMap:
 		return "persistent map"
 	default:
-		return "unknown"
+		return unknownEnum(int(c))
 	}
 }
 
@@ -319,8 +368,8 @@ type CleaningOptions struct {
 	CleaningMode   CleaningMode
 	NavigationMode NavigationMode
 	Category       *Category
-	BoundaryID     int
-	MapID          int
+	BoundaryID     string
+	MapID          string
 }
 
 func NewCleaningOptions() *CleaningOptions {
@@ -331,87 +380,116 @@ func NewCleaningOptions() *CleaningOptions {
 	}
 }
 
-func (r *Robot) Start(opts *CleaningOptions) error {
+func (r *Robot) Start(ctx context.Context, opts *CleaningOptions) error {
 	if opts == nil {
 		opts = NewCleaningOptions()
 	}
-	state, err := r.State()
+	caps, err := r.Capabilities(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get robot state: %w", err)
+		return err
 	}
-	serviceVersion := state.AvailableServices.HouseCleaning
-	if opts.Category == nil {
-		if serviceVersion == "basic-3" || serviceVersion == "basic-4" {
-			opts.Category = &CategoryPersistentMap
-		}
+	cmd, err := caps.StartCleaningCommand(opts)
+	if err != nil {
+		return fmt.Errorf("cannot build start request: %w", err)
+	}
+	if _, err := r.Do(ctx, cmd); err != nil {
+		return fmt.Errorf("start request failed: %w", err)
+	}
+	return nil
+}
+
+type SpotCleaningOptions struct {
+	CleaningMode   CleaningMode
+	NavigationMode NavigationMode
+	// Double makes the robot clean the spot twice.
+	Double bool
+	// Width and Height of the spot, in centimeters.
+	Width  int
+	Height int
+}
+
+func NewSpotCleaningOptions() *SpotCleaningOptions {
+	return &SpotCleaningOptions{
+		CleaningMode:   CleaningModeEco,
+		NavigationMode: NavigationModeNormal,
+		Width:          200,
+		Height:         200,
 	}
-	dataMap := map[string]interface{}{
-		"reqId": "1",
-		"cmd":   "startCleaning",
-		"params": map[string]interface{}{
-			"category": strconv.FormatInt(int64(*opts.Category), 10),
-		},
+}
+
+func (r *Robot) StartSpot(ctx context.Context, opts *SpotCleaningOptions) error {
+	if opts == nil {
+		opts = NewSpotCleaningOptions()
 	}
-	switch serviceVersion {
-	case "basic-1":
-		dataMap["params"].(map[string]interface{})["mode"] = int(opts.CleaningMode)
-		dataMap["params"].(map[string]interface{})["modifier"] = 1
-	case "basic-2":
-		dataMap["params"].(map[string]interface{})["mode"] = int(opts.CleaningMode)
-		dataMap["params"].(map[string]interface{})["modifier"] = 1
-		dataMap["params"].(map[string]interface{})["navigationMode"] = opts.NavigationMode
-	case "minimal-2":
-		dataMap["params"].(map[string]interface{})["navigationMode"] = opts.NavigationMode
-	case "basic-3", "basic-4":
-		dataMap["params"].(map[string]interface{})["mode"] = int(opts.CleaningMode)
-		dataMap["params"].(map[string]interface{})["modifier"] = 1
-		dataMap["params"].(map[string]interface{})["navigationMode"] = opts.NavigationMode
+	caps, err := r.Capabilities(ctx)
+	if err != nil {
+		return err
 	}
-	var resp RobotState
-	if err := r.post(dataMap, &resp); err != nil {
-		return fmt.Errorf("start request failed: %w", err)
+	cmd, err := caps.SpotCleaningCommand(opts)
+	if err != nil {
+		return fmt.Errorf("cannot build spot cleaning request: %w", err)
 	}
-	if resp.Result != "ok" {
-		return fmt.Errorf("start request failed: %s", resp.Result)
+	if _, err := r.Do(ctx, cmd); err != nil {
+		return fmt.Errorf("spot cleaning request failed: %w", err)
 	}
 	return nil
 }
 
-func (r *Robot) Stop() error {
-	dataMap := map[string]interface{}{
-		"reqId": "1",
-		"cmd":   "stopCleaning",
-	}
-	var resp RobotState
-	if err := r.post(dataMap, &resp); err != nil {
+func (r *Robot) Stop(ctx context.Context) error {
+	if _, err := r.Do(ctx, &StopCleaningCommand{}); err != nil {
 		return fmt.Errorf("stop request failed: %w", err)
 	}
-	if resp.Result != "ok" {
-		return fmt.Errorf("stop request failed: %s", resp.Result)
+	return nil
+}
+
+// Pause pauses the current cleaning run.
+func (r *Robot) Pause(ctx context.Context) error {
+	if _, err := r.Do(ctx, &PauseCleaningCommand{}); err != nil {
+		return fmt.Errorf("pause request failed: %w", err)
+	}
+	return nil
+}
+
+// Resume resumes a paused cleaning run.
+func (r *Robot) Resume(ctx context.Context) error {
+	if _, err := r.Do(ctx, &ResumeCleaningCommand{}); err != nil {
+		return fmt.Errorf("resume request failed: %w", err)
 	}
 	return nil
 }
 
-func (r *Robot) post(dataMap map[string]interface{}, response interface{}) error {
+// SendToBase sends the robot back to its charging base.
+func (r *Robot) SendToBase(ctx context.Context) error {
+	if _, err := r.Do(ctx, &SendToBaseCommand{}); err != nil {
+		return fmt.Errorf("send to base request failed: %w", err)
+	}
+	return nil
+}
+
+func (r *Robot) post(ctx context.Context, request interface{}, response interface{}) error {
 	// remove port from nucleo URL
 	uri, err := url.Parse(r.NucleoURL)
 	if err != nil {
 		return fmt.Errorf("failed to parse NucleoURL '%s': %v", r.NucleoURL, err)
 	}
-	host, _, err := net.SplitHostPort(uri.Host)
-	if err != nil {
-		return fmt.Errorf("failed to split host:port for '%s': %v", uri.Host, err)
+	if r.session == nil || !r.session.keepNucleoPort {
+		host, _, err := net.SplitHostPort(uri.Host)
+		if err != nil {
+			return fmt.Errorf("failed to split host:port for '%s': %v", uri.Host, err)
+		}
+		uri.Host = host
 	}
-	uri.Host = host
 	uri.Path += "/vendors/neato/robots/" + r.Serial + "/messages"
 
 	// skip TLS verification :( This will otherwise fail with the message
 	//   "x509: “*.neatocloud.com” certificate is not trusted"
 	skipVerification := true
 
-	body, err := json.Marshal(dataMap)
+	body, err := json.Marshal(request)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request body: %w", err)
 	}
-	return httpPost(uri.String(), r.Header(body), dataMap, skipVerification, response)
+	return r.guard(ctx, func() error {
+		return httpPost(ctx, uri.String(), r.Header(body), body, skipVerification, response)
+	})
 }
@@ -0,0 +1,182 @@
+package neato
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// Room is a friendly name for a zone of a persistent map.
+type Room struct {
+	Name            string `mapstructure:"name" yaml:"name" json:"name"`
+	RobotSerial     string `mapstructure:"serial" yaml:"serial" json:"serial"`
+	PersistentMapID string `mapstructure:"map_id" yaml:"map_id" json:"map_id"`
+	BoundaryID      string `mapstructure:"boundary_id" yaml:"boundary_id" json:"boundary_id"`
+}
+
+func (r *Room) String() string {
+	return fmt.Sprintf("Name: '%s', Serial: %s, Map ID: %s, Boundary ID: %s", r.Name, r.RobotSerial, r.PersistentMapID, r.BoundaryID)
+}
+
+// CleaningOptions returns the options to clean the room's zone.
+func (r *Room) CleaningOptions() *CleaningOptions {
+	opts := NewCleaningOptions()
+	opts.Category = &CategoryPersistentMap
+	opts.MapID = r.PersistentMapID
+	opts.BoundaryID = r.BoundaryID
+	return opts
+}
+
+// normalizeRoomName makes room lookups case-insensitive and treats dashes,
+// underscores and spaces the same way.
+func normalizeRoomName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.NewReplacer("-", " ", "_", " ").Replace(name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// RoomRegistry maps room names to zones. It is stored in the configuration
+// file under the "rooms" directive. It is safe for concurrent use.
+type RoomRegistry struct {
+	mu    sync.RWMutex
+	rooms map[string]*Room
+}
+
+func NewRoomRegistry() *RoomRegistry {
+	return &RoomRegistry{rooms: make(map[string]*Room)}
+}
+
+// LoadRooms reads the room registry from the configuration.
+func LoadRooms() (*RoomRegistry, error) {
+	var rooms []*Room
+	if err := viper.UnmarshalKey("rooms", &rooms); err != nil {
+		return nil, fmt.Errorf("failed to read rooms from configuration: %w", err)
+	}
+	reg := NewRoomRegistry()
+	for _, r := range rooms {
+		reg.Add(r)
+	}
+	return reg, nil
+}
+
+// Save writes the room registry to the configuration file.
+func (reg *RoomRegistry) Save() error {
+	viper.Set("rooms", reg.Rooms())
+	if err := viper.WriteConfig(); err != nil {
+		return fmt.Errorf("failed to write to file '%s': %w", viper.ConfigFileUsed(), err)
+	}
+	return nil
+}
+
+// Add adds a room, replacing any room with the same name. It returns false if
+// the room has no name.
+func (reg *RoomRegistry) Add(r *Room) bool {
+	return reg.add(r, true)
+}
+
+// add adds a room, replacing any room with the same name only if replace is
+// true. It returns whether the room was added.
+func (reg *RoomRegistry) add(r *Room, replace bool) bool {
+	key := normalizeRoomName(r.Name)
+	if key == "" {
+		return false
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if _, ok := reg.rooms[key]; ok && !replace {
+		return false
+	}
+	reg.rooms[key] = r
+	return true
+}
+
+func (reg *RoomRegistry) Remove(name string) bool {
+	key := normalizeRoomName(name)
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if _, ok := reg.rooms[key]; !ok {
+		return false
+	}
+	delete(reg.rooms, key)
+	return true
+}
+
+func (reg *RoomRegistry) Lookup(name string) (*Room, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	r, ok := reg.rooms[normalizeRoomName(name)]
+	return r, ok
+}
+
+// Rooms returns all the rooms, sorted by name.
+func (reg *RoomRegistry) Rooms() []*Room {
+	reg.mu.RLock()
+	rooms := make([]*Room, 0, len(reg.rooms))
+	for _, r := range reg.rooms {
+		rooms = append(rooms, r)
+	}
+	reg.mu.RUnlock()
+	sort.Slice(rooms, func(i, j int) bool {
+		return normalizeRoomName(rooms[i].Name) < normalizeRoomName(rooms[j].Name)
+	})
+	return rooms
+}
+
+// DiscoverRooms returns a room for every named zone of the persistent maps
+// of the robot.
+func (r *Robot) DiscoverRooms(ctx context.Context) ([]*Room, error) {
+	pms, err := r.PersistentMaps()
+	if err != nil {
+		return nil, err
+	}
+	var rooms []*Room
+	for _, pm := range pms {
+		boundaries, err := r.MapBoundaries(ctx, pm.ID)
+		if err != nil {
+			return nil, fmt.Errorf("persistent map '%s': %w", pm.ID, err)
+		}
+		for _, b := range boundaries {
+			if b.Type != BoundaryTypePolygon || strings.TrimSpace(b.Name) == "" {
+				continue
+			}
+			rooms = append(rooms, &Room{
+				Name:            strings.TrimSpace(b.Name),
+				RobotSerial:     r.Serial,
+				PersistentMapID: pm.ID,
+				BoundaryID:      b.ID,
+			})
+		}
+	}
+	return rooms, nil
+}
+
+// Discover adds the rooms found on every robot of the account. Rooms that
+// are already in the registry are kept, so that manual entries win over
+// boundary names. Robots that fail are skipped, and their errors are
+// returned together once every robot was tried. It returns the number of
+// added rooms.
+func (reg *RoomRegistry) Discover(ctx context.Context, acc *Account) (int, error) {
+	robots, err := acc.Robots()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get robots: %w", err)
+	}
+	added := 0
+	errs := make(map[string]error)
+	for _, robot := range robots {
+		rooms, err := robot.DiscoverRooms(ctx)
+		if err != nil {
+			errs[robot.Serial] = fmt.Errorf("failed to discover rooms: %w", err)
+			continue
+		}
+		for _, room := range rooms {
+			if reg.add(room, false) {
+				added++
+			}
+		}
+	}
+	return added, joinRobotErrors(errs)
+}
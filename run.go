@@ -0,0 +1,242 @@
+package neato
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	DefaultMinPollInterval = 5 * time.Second
+	DefaultMaxPollInterval = 60 * time.Second
+	DefaultStartTimeout    = 2 * time.Minute
+	DefaultMapRetries      = 5
+	DefaultMapRetryDelay   = 10 * time.Second
+)
+
+// ErrRunMapUnavailable is returned together with the summary by
+// StartAndWait when the run completed, but its map could not be fetched.
+var ErrRunMapUnavailable = errors.New("failed to get the map of the run")
+
+// WaitOptions tune how StartAndWait follows a run.
+type WaitOptions struct {
+	// MinPollInterval and MaxPollInterval bound the adaptive polling:
+	// polling starts fast, slows down while nothing changes, and goes back
+	// to fast on every transition.
+	MinPollInterval time.Duration
+	MaxPollInterval time.Duration
+	// StartTimeout is how long to wait for the robot to start cleaning
+	// after the start command. Zero means no limit.
+	StartTimeout time.Duration
+	// MapRetries is how many more times the map of the completed run is
+	// fetched if it is not available yet, waiting MapRetryDelay before the
+	// first retry and doubling the delay every time.
+	MapRetries    int
+	MapRetryDelay time.Duration
+}
+
+func NewWaitOptions() *WaitOptions {
+	return &WaitOptions{
+		MinPollInterval: DefaultMinPollInterval,
+		MaxPollInterval: DefaultMaxPollInterval,
+		StartTimeout:    DefaultStartTimeout,
+		MapRetries:      DefaultMapRetries,
+		MapRetryDelay:   DefaultMapRetryDelay,
+	}
+}
+
+type RunPhase int
+
+var (
+	RunPhaseStarting             RunPhase = 0
+	RunPhaseCleaning             RunPhase = 1
+	RunPhasePaused               RunPhase = 2
+	RunPhaseSuspendedForRecharge RunPhase = 3
+	RunPhaseReturningToBase      RunPhase = 4
+	RunPhaseDocked               RunPhase = 5
+	RunPhaseIdle                 RunPhase = 6
+	RunPhaseError                RunPhase = 7
+)
+
+func (p RunPhase) String() string {
+	switch p {
+	case RunPhaseStarting:
+		return "starting"
+	case RunPhaseCleaning:
+		return "cleaning"
+	case RunPhasePaused:
+		return "paused"
+	case RunPhaseSuspendedForRecharge:
+		return "suspended for recharge"
+	case RunPhaseReturningToBase:
+		return "returning to base"
+	case RunPhaseDocked:
+		return "docked"
+	case RunPhaseIdle:
+		return "idle"
+	case RunPhaseError:
+		return "error"
+	default:
+		return unknownEnum(int(p))
+	}
+}
+
+// phaseOf returns the phase of a cleaning run from the robot state.
+func phaseOf(s *RobotState) RunPhase {
+	switch s.State {
+	case StateBusy:
+		switch s.Action {
+		case ActionSuspendedCleaning, ActionSuspendedExploration:
+			return RunPhaseSuspendedForRecharge
+		case ActionDocking:
+			return RunPhaseReturningToBase
+		default:
+			return RunPhaseCleaning
+		}
+	case StatePaused:
+		return RunPhasePaused
+	case StateError:
+		return RunPhaseError
+	case StateIdle:
+		if s.Details.IsDocked {
+			return RunPhaseDocked
+		}
+		return RunPhaseIdle
+	default:
+		return RunPhaseStarting
+	}
+}
+
+// Progress is reported by StartAndWait on every phase transition.
+type Progress struct {
+	Phase   RunPhase
+	State   *RobotState
+	Elapsed time.Duration
+}
+
+// RunSummary describes a completed cleaning run.
+type RunSummary struct {
+	StartedAt  time.Time
+	FinishedAt time.Time
+	// Map is the newest map record of the robot, or nil if the run has not
+	// produced one yet.
+	Map *Map
+}
+
+func (s *RunSummary) Duration() time.Duration {
+	return s.FinishedAt.Sub(s.StartedAt)
+}
+
+func (s *RunSummary) String() string {
+	str := fmt.Sprintf("Duration: %s", s.Duration().Round(time.Second))
+	if s.Map != nil {
+		str += ", Map: " + s.Map.String()
+	}
+	return str
+}
+
+// StartAndWait starts cleaning and blocks until the robot is done and back
+// to base. onProgress, if not nil, is called on every phase transition. If
+// wait is nil, NewWaitOptions is used.
+//
+// Once the run is completed, its map is fetched. If that fails, the summary
+// is returned without map together with an error wrapping
+// ErrRunMapUnavailable.
+func (r *Robot) StartAndWait(ctx context.Context, opts *CleaningOptions, wait *WaitOptions, onProgress func(Progress)) (*RunSummary, error) {
+	if wait == nil {
+		wait = NewWaitOptions()
+	}
+	minInterval, maxInterval := wait.MinPollInterval, wait.MaxPollInterval
+	if minInterval <= 0 {
+		minInterval = DefaultMinPollInterval
+	}
+	if maxInterval < minInterval {
+		maxInterval = minInterval
+	}
+	startedAt := time.Now()
+	if err := r.Start(ctx, opts); err != nil {
+		return nil, err
+	}
+	phase := RunPhaseStarting
+	if onProgress != nil {
+		onProgress(Progress{Phase: phase, Elapsed: time.Since(startedAt)})
+	}
+
+	started := false
+	interval := minInterval
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		state, err := r.State(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if err := stateError(state); err != nil {
+			return nil, err
+		}
+		newPhase := phaseOf(state)
+		if newPhase == RunPhaseCleaning || newPhase == RunPhasePaused || newPhase == RunPhaseSuspendedForRecharge || newPhase == RunPhaseReturningToBase {
+			started = true
+		}
+		if !started && wait.StartTimeout > 0 && time.Since(startedAt) > wait.StartTimeout {
+			return nil, fmt.Errorf("robot did not start cleaning within %s", wait.StartTimeout)
+		}
+		if newPhase != phase {
+			phase = newPhase
+			interval = minInterval
+			if onProgress != nil {
+				onProgress(Progress{Phase: phase, State: state, Elapsed: time.Since(startedAt)})
+			}
+		} else {
+			interval = interval * 3 / 2
+			if interval > maxInterval {
+				interval = maxInterval
+			}
+		}
+		if started && isDone(state) {
+			break
+		}
+	}
+
+	summary := RunSummary{StartedAt: startedAt, FinishedAt: time.Now()}
+	m, err := r.runMap(ctx, startedAt, wait)
+	if err != nil {
+		return &summary, fmt.Errorf("run completed, but %w: %v", ErrRunMapUnavailable, err)
+	}
+	summary.Map = m
+	return &summary, nil
+}
+
+// runMap returns the map of the run started at startedAt, retrying with
+// backoff since the cloud may take a while to process it. It returns a nil
+// map without error if the run produced no map after all the retries.
+func (r *Robot) runMap(ctx context.Context, startedAt time.Time, wait *WaitOptions) (*Map, error) {
+	delay := wait.MapRetryDelay
+	if delay <= 0 {
+		delay = DefaultMapRetryDelay
+	}
+	for attempt := 0; ; attempt++ {
+		maps, err := r.RefreshMaps(ctx)
+		if err == nil && len(maps) > 0 {
+			// the newest map is the first one, if it was started after
+			// this run (give or take some clock skew between us and the
+			// cloud)
+			if start, err := maps[0].StartTime(); err == nil && start.After(startedAt.Add(-5*time.Minute)) {
+				return maps[0], nil
+			}
+		}
+		if attempt >= wait.MapRetries {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
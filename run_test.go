@@ -0,0 +1,43 @@
+package neato
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunMapRetries(t *testing.T) {
+	// the fake cloud has a single map, started at 2026-10-19T10:00:00Z
+	mapStart := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name      string
+		startedAt time.Time
+		wantMap   bool
+		wantCalls int32
+	}{
+		{"map of the run", mapStart.Add(-time.Minute), true, 1},
+		{"map of an older run", mapStart.Add(time.Hour), false, 3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFakeCloud(t)
+			robots, err := f.account(nil).Robots()
+			if err != nil {
+				t.Fatal(err)
+			}
+			wait := NewWaitOptions()
+			wait.MapRetries = 2
+			wait.MapRetryDelay = time.Millisecond
+			m, err := robots[0].runMap(context.Background(), tc.startedAt, wait)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (m != nil) != tc.wantMap {
+				t.Errorf("got map %v, want one: %v", m, tc.wantMap)
+			}
+			if got := atomic.LoadInt32(&f.mapsCalls); got != tc.wantCalls {
+				t.Errorf("got %d maps requests, want %d", got, tc.wantCalls)
+			}
+		})
+	}
+}
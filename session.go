@@ -1,10 +1,16 @@
 package neato
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -18,9 +24,16 @@ func NewPasswordSession(endpoint string, header *url.Values) *PasswordSession {
 	}
 }
 
+// PasswordSession is safe for concurrent use. Login may be called while other
+// requests are in flight, which keep using the previous token.
 type PasswordSession struct {
 	endpoint string
-	header   *url.Values
+	// keepNucleoPort keeps the port of the robots' NucleoURL, so that tests
+	// can use a local fake Nucleo server.
+	keepNucleoPort bool
+
+	mu     sync.RWMutex
+	header *url.Values
 }
 
 func (s *PasswordSession) Login(email, password string) error {
@@ -44,16 +57,17 @@ func (s *PasswordSession) Login(email, password string) error {
 	if err := s.post(uri, data, &resp); err != nil {
 		return fmt.Errorf("http post failed: %w", err)
 	}
-	if s.header == nil {
-		s.header = &url.Values{}
-	}
-	s.header.Set("Authorization", fmt.Sprintf("Token token=%s", resp.AccessToken))
+	header := s.headerCopy()
+	header.Set("Authorization", fmt.Sprintf("Token token=%s", resp.AccessToken))
+	s.mu.Lock()
+	s.header = header
+	s.mu.Unlock()
 	return nil
 }
 
 func (s *PasswordSession) SaveConfig() error {
 	viper.Set("session.endpoint", s.endpoint)
-	viper.Set("session.header", s.header)
+	viper.Set("session.header", s.headerCopy())
 	if err := viper.WriteConfig(); err != nil {
 		return fmt.Errorf("failed to write to file '%s': %w", viper.ConfigFileUsed(), err)
 	}
@@ -61,9 +75,55 @@ func (s *PasswordSession) SaveConfig() error {
 }
 
 func (s *PasswordSession) post(path string, dataMap map[string]interface{}, response interface{}) error {
-	return httpPost(s.endpoint+"/"+path, s.header, dataMap, false, response)
+	data, err := json.Marshal(dataMap)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request data to JSON: %w", err)
+	}
+	start := time.Now()
+	err = httpPost(context.Background(), s.endpoint+"/"+path, s.headerCopy(), data, false, response)
+	observeAPICall("POST "+endpointName(path), start, err)
+	return err
 }
 
-func (s *PasswordSession) get(path string, response interface{}) error {
-	return httpGet(s.endpoint+"/"+path, s.header, false, response)
+func (s *PasswordSession) get(ctx context.Context, path string, response interface{}) error {
+	start := time.Now()
+	err := httpGet(ctx, s.endpoint+"/"+path, s.headerCopy(), false, response)
+	observeAPICall("GET "+endpointName(path), start, err)
+	return err
+}
+
+// endpointName replaces the robot serial in a Beehive path with a
+// placeholder, so that requests for different robots share an endpoint.
+func endpointName(path string) string {
+	parts := strings.Split(path, "/")
+	for i := 1; i < len(parts); i++ {
+		if parts[i-1] == "robots" {
+			parts[i] = "{serial}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// cacheScope identifies the endpoint and the credentials of the session, so
+// that cached responses of different accounts or tokens are never mixed up.
+func (s *PasswordSession) cacheScope() string {
+	h := sha256.New()
+	h.Write([]byte(s.endpoint))
+	h.Write([]byte{0})
+	h.Write([]byte(s.headerCopy().Get("Authorization")))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// headerCopy returns a copy of the session header, so that it can be used
+// while Login replaces it.
+func (s *PasswordSession) headerCopy() *url.Values {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	header := url.Values{}
+	if s.header != nil {
+		for k, v := range *s.header {
+			header[k] = append([]string(nil), v...)
+		}
+	}
+	return &header
 }
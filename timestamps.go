@@ -0,0 +1,124 @@
+package neato
+
+import (
+	"fmt"
+	"time"
+)
+
+// parseTimestamp parses the RFC3339 timestamps returned by the Beehive API.
+// A nil or empty timestamp returns the zero time and no error.
+func parseTimestamp(s *string) (time.Time, error) {
+	if s == nil || *s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, *s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp '%s': %w", *s, err)
+	}
+	return t, nil
+}
+
+func secondsToDuration(s *int) time.Duration {
+	if s == nil {
+		return 0
+	}
+	return time.Duration(*s) * time.Second
+}
+
+// PurchasedTime returns PurchasedAt as time.Time, or the zero time if it is
+// not set.
+func (r *Robot) PurchasedTime() (time.Time, error) {
+	return parseTimestamp(r.PurchasedAt)
+}
+
+// LinkedTime returns LinkedAt as time.Time, or the zero time if it is not set.
+func (r *Robot) LinkedTime() (time.Time, error) {
+	return parseTimestamp(r.LinkedAt)
+}
+
+// CreatedTime returns CreatedAt as time.Time, or the zero time if it is not
+// set.
+func (r *Robot) CreatedTime() (time.Time, error) {
+	return parseTimestamp(r.CreatedAt)
+}
+
+// Location returns the robot's time zone. If the robot has no time zone set,
+// UTC is returned.
+func (r *Robot) Location() (*time.Location, error) {
+	if r.Timezone == nil || *r.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(*r.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone '%s': %w", *r.Timezone, err)
+	}
+	return loc, nil
+}
+
+// LocalTime converts t to the robot's time zone.
+func (r *Robot) LocalTime(t time.Time) (time.Time, error) {
+	loc, err := r.Location()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(loc), nil
+}
+
+// StartTime returns StartAt as time.Time, or the zero time if it is not set.
+func (m *Map) StartTime() (time.Time, error) {
+	return parseTimestamp(m.StartAt)
+}
+
+// EndTime returns EndAt as time.Time, or the zero time if it is not set.
+func (m *Map) EndTime() (time.Time, error) {
+	return parseTimestamp(m.EndAt)
+}
+
+// GeneratedTime returns GeneratedAt as time.Time, or the zero time if it is
+// not set.
+func (m *Map) GeneratedTime() (time.Time, error) {
+	return parseTimestamp(m.GeneratedAt)
+}
+
+// RunDuration returns the time between the start and the end of the run,
+// including pauses, errors and recharges.
+func (m *Map) RunDuration() (time.Duration, error) {
+	start, err := m.StartTime()
+	if err != nil {
+		return 0, err
+	}
+	end, err := m.EndTime()
+	if err != nil {
+		return 0, err
+	}
+	if start.IsZero() || end.IsZero() {
+		return 0, fmt.Errorf("map '%s' has no start or end time", m.ID)
+	}
+	return end.Sub(start), nil
+}
+
+func (m *Map) PauseDuration() time.Duration {
+	return secondsToDuration(m.TimeInPause)
+}
+
+func (m *Map) ErrorDuration() time.Duration {
+	return secondsToDuration(m.TimeInError)
+}
+
+func (m *Map) SuspendedCleaningDuration() time.Duration {
+	return secondsToDuration(m.TimeInSuspendedCleaning)
+}
+
+// CleaningDuration returns the run duration minus the time spent paused, in
+// error or suspended for recharging.
+func (m *Map) CleaningDuration() (time.Duration, error) {
+	d, err := m.RunDuration()
+	if err != nil {
+		return 0, err
+	}
+	d -= m.PauseDuration() + m.ErrorDuration() + m.SuspendedCleaningDuration()
+	if d < 0 {
+		d = 0
+	}
+	return d, nil
+}
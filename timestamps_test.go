@@ -0,0 +1,127 @@
+package neato
+
+import (
+	"testing"
+	"time"
+)
+
+func strPtr(s string) *string { return &s }
+
+func intPtr(i int) *int { return &i }
+
+func TestParseTimestamp(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		s       *string
+		want    time.Time
+		wantErr bool
+	}{
+		{"nil", nil, time.Time{}, false},
+		{"empty", strPtr(""), time.Time{}, false},
+		{"utc", strPtr("2026-10-19T10:00:00Z"), time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC), false},
+		{"offset", strPtr("2026-10-19T12:00:00+02:00"), time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC), false},
+		{"date only", strPtr("2026-10-19"), time.Time{}, true},
+		{"garbage", strPtr("yesterday"), time.Time{}, true},
+	} {
+		got, err := parseTimestamp(tc.s)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: got error %v, want error: %v", tc.name, err, tc.wantErr)
+			continue
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestRobotLocation(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		timezone *string
+		want     string
+		wantErr  bool
+	}{
+		{"nil", nil, "UTC", false},
+		{"empty", strPtr(""), "UTC", false},
+		{"valid", strPtr("Europe/Dublin"), "Europe/Dublin", false},
+		{"invalid", strPtr("Mars/Olympus_Mons"), "", true},
+	} {
+		r := Robot{Timezone: tc.timezone}
+		loc, err := r.Location()
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: got error %v, want error: %v", tc.name, err, tc.wantErr)
+			continue
+		}
+		if err == nil && loc.String() != tc.want {
+			t.Errorf("%s: got location %s, want %s", tc.name, loc, tc.want)
+		}
+	}
+}
+
+func TestMapDurations(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		m        *Map
+		run      time.Duration
+		cleaning time.Duration
+		wantErr  bool
+	}{
+		{
+			name:    "missing start",
+			m:       &Map{EndAt: strPtr("2026-10-19T11:00:00Z")},
+			wantErr: true,
+		},
+		{
+			name:    "missing end",
+			m:       &Map{StartAt: strPtr("2026-10-19T10:00:00Z")},
+			wantErr: true,
+		},
+		{
+			name:    "invalid end",
+			m:       &Map{StartAt: strPtr("2026-10-19T10:00:00Z"), EndAt: strPtr("11:00")},
+			wantErr: true,
+		},
+		{
+			name:     "no pauses",
+			m:        &Map{StartAt: strPtr("2026-10-19T10:00:00Z"), EndAt: strPtr("2026-10-19T11:00:00Z")},
+			run:      time.Hour,
+			cleaning: time.Hour,
+		},
+		{
+			name: "pauses, errors and recharges",
+			m: &Map{
+				StartAt:                 strPtr("2026-10-19T10:00:00Z"),
+				EndAt:                   strPtr("2026-10-19T11:00:00Z"),
+				TimeInPause:             intPtr(600),
+				TimeInError:             intPtr(300),
+				TimeInSuspendedCleaning: intPtr(900),
+			},
+			run:      time.Hour,
+			cleaning: 30 * time.Minute,
+		},
+		{
+			name: "more pause than run",
+			m: &Map{
+				StartAt:     strPtr("2026-10-19T10:00:00Z"),
+				EndAt:       strPtr("2026-10-19T10:10:00Z"),
+				TimeInPause: intPtr(3600),
+			},
+			run:      10 * time.Minute,
+			cleaning: 0,
+		},
+	} {
+		run, err := tc.m.RunDuration()
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: RunDuration() error %v, want error: %v", tc.name, err, tc.wantErr)
+			continue
+		}
+		cleaning, err := tc.m.CleaningDuration()
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: CleaningDuration() error %v, want error: %v", tc.name, err, tc.wantErr)
+			continue
+		}
+		if run != tc.run || cleaning != tc.cleaning {
+			t.Errorf("%s: got run %v and cleaning %v, want %v and %v", tc.name, run, cleaning, tc.run, tc.cleaning)
+		}
+	}
+}
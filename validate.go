@@ -0,0 +1,339 @@
+package neato
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// BoundaryLimits are the maximum number of boundaries a persistent map can
+// hold. Advisory limits are not known to be enforced by the robot, so
+// exceeding them is reported as a warning instead of an error.
+type BoundaryLimits struct {
+	MaxZones     int
+	MaxNoGoLines int
+	MaxVertices  int
+	Advisory     bool
+}
+
+// DefaultBoundaryLimits are used for robots that are not in
+// ModelBoundaryLimits. Neato does not publish the limits of its firmware, so
+// these are conservative values, well within what the Neato app lets users
+// draw, and they are only advisory.
+var DefaultBoundaryLimits = BoundaryLimits{
+	MaxZones:     10,
+	MaxNoGoLines: 20,
+	MaxVertices:  20,
+	Advisory:     true,
+}
+
+// ModelBoundaryLimit are the boundary limits of a robot model, starting from
+// the firmware version MinFirmware. An empty MinFirmware matches every
+// firmware version.
+type ModelBoundaryLimit struct {
+	Model       string
+	MinFirmware string
+	Limits      BoundaryLimits
+}
+
+// ModelBoundaryLimits is the table used by LookupBoundaryLimits. Models are
+// the RobotState.Meta.ModelName values, matched case-insensitively. It is
+// empty by default, since no limits have been confirmed for any model yet:
+// add entries for the limits verified against a robot, to have them
+// enforced.
+var ModelBoundaryLimits []ModelBoundaryLimit
+
+// LookupBoundaryLimits returns the limits of the entry of
+// ModelBoundaryLimits for model with the highest MinFirmware not above
+// firmware, or DefaultBoundaryLimits if there is none.
+func LookupBoundaryLimits(model, firmware string) BoundaryLimits {
+	var best *ModelBoundaryLimit
+	for i := range ModelBoundaryLimits {
+		e := &ModelBoundaryLimits[i]
+		if !strings.EqualFold(e.Model, model) {
+			continue
+		}
+		if e.MinFirmware != "" && compareVersions(firmware, e.MinFirmware) < 0 {
+			continue
+		}
+		if best == nil || compareVersions(e.MinFirmware, best.MinFirmware) > 0 {
+			best = e
+		}
+	}
+	if best == nil {
+		return DefaultBoundaryLimits
+	}
+	return best.Limits
+}
+
+// compareVersions compares the numeric components of two firmware versions
+// like "4.5.3-189", returning -1, 0 or 1. Missing components count as 0.
+func compareVersions(a, b string) int {
+	split := func(v string) []int {
+		var nums []int
+		for _, f := range strings.FieldsFunc(v, func(r rune) bool { return r < '0' || r > '9' }) {
+			n, err := strconv.Atoi(f)
+			if err != nil {
+				n = 0
+			}
+			nums = append(nums, n)
+		}
+		return nums
+	}
+	va, vb := split(a), split(b)
+	for i := 0; i < len(va) || i < len(vb); i++ {
+		var x, y int
+		if i < len(va) {
+			x = va[i]
+		}
+		if i < len(vb) {
+			y = vb[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// BoundaryLimits returns the boundary limits of the robot model and
+// firmware. Robots that cannot clean zones only accept no-go lines.
+func (c *Capabilities) BoundaryLimits() BoundaryLimits {
+	limits := LookupBoundaryLimits(c.Model, c.Firmware)
+	if !c.SupportsZones() {
+		limits.MaxZones = 0
+	}
+	return limits
+}
+
+type BoundaryProblemKind string
+
+var (
+	BoundaryProblemUnknownType      BoundaryProblemKind = "unknown_type"
+	BoundaryProblemDegenerate       BoundaryProblemKind = "degenerate"
+	BoundaryProblemSelfIntersection BoundaryProblemKind = "self_intersection"
+	BoundaryProblemOutOfRange       BoundaryProblemKind = "out_of_range"
+	BoundaryProblemDuplicateID      BoundaryProblemKind = "duplicate_id"
+	BoundaryProblemTooMany          BoundaryProblemKind = "too_many"
+)
+
+// BoundaryProblem is an issue found by ValidateBoundaries. Index is the
+// position of the boundary in the validated list, or -1 for problems that
+// concern the whole list.
+type BoundaryProblem struct {
+	Index      int
+	BoundaryID string
+	Kind       BoundaryProblemKind
+	Severity   Severity
+	Message    string
+}
+
+func (p BoundaryProblem) String() string {
+	if p.Index < 0 {
+		return fmt.Sprintf("%s: %s", p.Severity, p.Message)
+	}
+	return fmt.Sprintf("%s: boundary #%d (ID: '%s'): %s", p.Severity, p.Index, p.BoundaryID, p.Message)
+}
+
+// BoundaryProblems is the list of problems found by ValidateBoundaries. It
+// can be used as an error.
+type BoundaryProblems []BoundaryProblem
+
+func (p BoundaryProblems) Error() string {
+	msgs := make([]string, 0, len(p))
+	for _, problem := range p {
+		msgs = append(msgs, problem.String())
+	}
+	return fmt.Sprintf("%d boundary problem(s): %s", len(p), strings.Join(msgs, "; "))
+}
+
+// HasErrors reports whether any problem would make the robot reject the
+// boundaries.
+func (p BoundaryProblems) HasErrors() bool {
+	for _, problem := range p {
+		if problem.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateBoundaries checks zones and no-go lines before they are sent to a
+// robot: known type, enough distinct vertices, no self-intersections,
+// coordinates within the normalized [0, 1] range, unique IDs and counts
+// within limits. Counts above advisory limits are only warnings.
+func ValidateBoundaries(boundaries []*Boundary, limits BoundaryLimits) BoundaryProblems {
+	var problems BoundaryProblems
+	add := func(idx int, b *Boundary, kind BoundaryProblemKind, sev Severity, format string, args ...interface{}) {
+		p := BoundaryProblem{Index: idx, Kind: kind, Severity: sev, Message: fmt.Sprintf(format, args...)}
+		if b != nil {
+			p.BoundaryID = b.ID
+		}
+		problems = append(problems, p)
+	}
+
+	limitSeverity := SeverityError
+	if limits.Advisory {
+		limitSeverity = SeverityWarning
+	}
+	ids := make(map[string]int)
+	zones, lines := 0, 0
+	for idx, b := range boundaries {
+		if b.ID != "" {
+			if prev, ok := ids[b.ID]; ok {
+				add(idx, b, BoundaryProblemDuplicateID, SeverityError, "duplicate ID, already used by boundary #%d", prev)
+			} else {
+				ids[b.ID] = idx
+			}
+		}
+		for vidx, v := range b.Vertices {
+			if math.IsNaN(v[0]) || math.IsNaN(v[1]) || v[0] < 0 || v[0] > 1 || v[1] < 0 || v[1] > 1 {
+				add(idx, b, BoundaryProblemOutOfRange, SeverityError, "vertex #%d (%g, %g) is outside of the [0, 1] range", vidx, v[0], v[1])
+			}
+		}
+		if limits.MaxVertices > 0 && len(b.Vertices) > limits.MaxVertices {
+			add(idx, b, BoundaryProblemTooMany, limitSeverity, "%d vertices, at most %d are allowed", len(b.Vertices), limits.MaxVertices)
+		}
+		vertices := dedupVertices(b.Vertices)
+		switch b.Type {
+		case BoundaryTypePolygon:
+			zones++
+			if len(vertices) > 1 && vertices[0] == vertices[len(vertices)-1] {
+				vertices = vertices[:len(vertices)-1]
+			}
+			if len(vertices) < 3 {
+				add(idx, b, BoundaryProblemDegenerate, SeverityError, "zone has %d distinct vertices, at least 3 are needed", len(vertices))
+			} else if i, j, ok := selfIntersection(vertices, true); ok {
+				add(idx, b, BoundaryProblemSelfIntersection, SeverityError, "edges #%d and #%d intersect", i, j)
+			} else if math.Abs(polygonArea(vertices)) < 1e-9 {
+				add(idx, b, BoundaryProblemDegenerate, SeverityError, "zone has no area")
+			}
+		case BoundaryTypePolyline:
+			lines++
+			if len(vertices) < 2 {
+				add(idx, b, BoundaryProblemDegenerate, SeverityError, "no-go line has %d distinct vertices, at least 2 are needed", len(vertices))
+			} else if i, j, ok := selfIntersection(vertices, false); ok {
+				add(idx, b, BoundaryProblemSelfIntersection, SeverityWarning, "segments #%d and #%d intersect", i, j)
+			}
+		default:
+			add(idx, b, BoundaryProblemUnknownType, SeverityError, "unknown type '%s', must be '%s' or '%s'", b.Type, BoundaryTypePolygon, BoundaryTypePolyline)
+		}
+	}
+	if zones > limits.MaxZones {
+		add(-1, nil, BoundaryProblemTooMany, limitSeverity, "%d zones, at most %d are allowed", zones, limits.MaxZones)
+	}
+	if lines > limits.MaxNoGoLines {
+		add(-1, nil, BoundaryProblemTooMany, limitSeverity, "%d no-go lines, at most %d are allowed", lines, limits.MaxNoGoLines)
+	}
+	return problems
+}
+
+// dedupVertices removes consecutive duplicate vertices.
+func dedupVertices(vertices [][2]float64) [][2]float64 {
+	var out [][2]float64
+	for _, v := range vertices {
+		if len(out) > 0 && out[len(out)-1] == v {
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// polygonArea returns the signed area of a polygon with the shoelace
+// formula.
+func polygonArea(vertices [][2]float64) float64 {
+	area := 0.0
+	for i := range vertices {
+		a, b := vertices[i], vertices[(i+1)%len(vertices)]
+		area += a[0]*b[1] - b[0]*a[1]
+	}
+	return area / 2
+}
+
+// selfIntersection returns the first pair of non-adjacent edges that
+// intersect. If closed is true, the last vertex is connected to the first.
+func selfIntersection(vertices [][2]float64, closed bool) (int, int, bool) {
+	n := len(vertices) - 1
+	if closed {
+		n = len(vertices)
+	}
+	edge := func(i int) ([2]float64, [2]float64) {
+		return vertices[i], vertices[(i+1)%len(vertices)]
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			adjacent := j == i+1 || (closed && i == 0 && j == n-1)
+			a1, a2 := edge(i)
+			b1, b2 := edge(j)
+			if adjacent {
+				// adjacent edges share a vertex, they only intersect
+				// if they overlap
+				if collinearOverlap(a1, a2, b1, b2) {
+					return i, j, true
+				}
+				continue
+			}
+			if segmentsIntersect(a1, a2, b1, b2) {
+				return i, j, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func cross(o, a, b [2]float64) float64 {
+	return (a[0]-o[0])*(b[1]-o[1]) - (a[1]-o[1])*(b[0]-o[0])
+}
+
+func onSegment(p, q, r [2]float64) bool {
+	return math.Min(p[0], r[0]) <= q[0] && q[0] <= math.Max(p[0], r[0]) &&
+		math.Min(p[1], r[1]) <= q[1] && q[1] <= math.Max(p[1], r[1])
+}
+
+func sign(f float64) int {
+	const eps = 1e-12
+	switch {
+	case f > eps:
+		return 1
+	case f < -eps:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func segmentsIntersect(p1, p2, q1, q2 [2]float64) bool {
+	d1 := sign(cross(q1, q2, p1))
+	d2 := sign(cross(q1, q2, p2))
+	d3 := sign(cross(p1, p2, q1))
+	d4 := sign(cross(p1, p2, q2))
+	if d1*d2 < 0 && d3*d4 < 0 {
+		return true
+	}
+	return (d1 == 0 && onSegment(q1, p1, q2)) ||
+		(d2 == 0 && onSegment(q1, p2, q2)) ||
+		(d3 == 0 && onSegment(p1, q1, p2)) ||
+		(d4 == 0 && onSegment(p1, q2, p2))
+}
+
+// collinearOverlap reports whether two segments sharing an endpoint fold
+// back onto each other.
+func collinearOverlap(p1, p2, q1, q2 [2]float64) bool {
+	if sign(cross(p1, p2, q1)) != 0 || sign(cross(p1, p2, q2)) != 0 {
+		return false
+	}
+	// the shared endpoint is p2 == q1 for consecutive edges, or q2 == p1
+	// for the closing edge
+	shared, a, b := p2, p1, q2
+	if q2 == p1 {
+		shared, a, b = p1, p2, q1
+	}
+	da := [2]float64{a[0] - shared[0], a[1] - shared[1]}
+	db := [2]float64{b[0] - shared[0], b[1] - shared[1]}
+	return da[0]*db[0]+da[1]*db[1] > 0
+}
@@ -0,0 +1,120 @@
+package neato
+
+import "testing"
+
+func TestLookupBoundaryLimits(t *testing.T) {
+	defer func(table []ModelBoundaryLimit) { ModelBoundaryLimits = table }(ModelBoundaryLimits)
+	older := BoundaryLimits{MaxZones: 5, MaxNoGoLines: 10, MaxVertices: 10}
+	newer := BoundaryLimits{MaxZones: 15, MaxNoGoLines: 10, MaxVertices: 10}
+	every := BoundaryLimits{MaxZones: 25, MaxNoGoLines: 25, MaxVertices: 25}
+	ModelBoundaryLimits = []ModelBoundaryLimit{
+		{Model: "modelA", MinFirmware: "4.0.0", Limits: older},
+		{Model: "modelA", MinFirmware: "4.5.0", Limits: newer},
+		{Model: "modelB", Limits: every},
+	}
+	for _, tc := range []struct {
+		model    string
+		firmware string
+		want     BoundaryLimits
+	}{
+		{"modelA", "4.5.3-189", newer},
+		{"ModelA", "4.5.0", newer},
+		{"modelA", "4.4.0-72", older},
+		{"modelA", "3.9", DefaultBoundaryLimits},
+		{"modelB", "", every},
+		{"unknown", "9.9", DefaultBoundaryLimits},
+		{"", "", DefaultBoundaryLimits},
+	} {
+		if got := LookupBoundaryLimits(tc.model, tc.firmware); got != tc.want {
+			t.Errorf("LookupBoundaryLimits(%q, %q) = %+v, want %+v", tc.model, tc.firmware, got, tc.want)
+		}
+	}
+}
+
+func TestDefaultBoundaryLimitsAdvisory(t *testing.T) {
+	if got := LookupBoundaryLimits("botvacD7Connected", "4.5.3"); got != DefaultBoundaryLimits || !got.Advisory {
+		t.Errorf("got %+v, want the advisory default limits for a model without verified limits", got)
+	}
+}
+
+func TestCapabilitiesBoundaryLimits(t *testing.T) {
+	state := RobotState{AvailableServices: AvailableServices{HouseCleaning: "basic-3"}}
+	state.Meta.ModelName = "botvacD7Connected"
+	state.Meta.Firmware = "4.5.3"
+	if got := state.Capabilities().BoundaryLimits().MaxZones; got != 0 {
+		t.Errorf("basic-3: MaxZones = %d, want 0", got)
+	}
+	state.AvailableServices.HouseCleaning = "basic-4"
+	if got := state.Capabilities().BoundaryLimits().MaxZones; got != DefaultBoundaryLimits.MaxZones {
+		t.Errorf("basic-4: MaxZones = %d, want %d", got, DefaultBoundaryLimits.MaxZones)
+	}
+}
+
+func TestValidateBoundaries(t *testing.T) {
+	zone := func(id string, vertices ...[2]float64) *Boundary {
+		return &Boundary{ID: id, Type: BoundaryTypePolygon, Vertices: vertices}
+	}
+	line := func(id string, vertices ...[2]float64) *Boundary {
+		return &Boundary{ID: id, Type: BoundaryTypePolyline, Vertices: vertices}
+	}
+	square := [][2]float64{{0.1, 0.1}, {0.2, 0.1}, {0.2, 0.2}, {0.1, 0.2}}
+	limits := BoundaryLimits{MaxZones: 1, MaxNoGoLines: 1, MaxVertices: 4}
+	advisory := limits
+	advisory.Advisory = true
+
+	for _, tc := range []struct {
+		name       string
+		boundaries []*Boundary
+		limits     BoundaryLimits
+		want       []BoundaryProblemKind
+		wantErrors bool
+	}{
+		{"valid", []*Boundary{zone("a", square...), line("b", [2]float64{0, 0}, [2]float64{1, 1})}, limits, nil, false},
+		{"unknown type", []*Boundary{{ID: "a", Type: "circle", Vertices: square}}, limits, []BoundaryProblemKind{BoundaryProblemUnknownType}, true},
+		{"degenerate zone", []*Boundary{zone("a", [2]float64{0, 0}, [2]float64{0.5, 0.5}, [2]float64{0.5, 0.5})}, limits, []BoundaryProblemKind{BoundaryProblemDegenerate}, true},
+		{"flat zone", []*Boundary{zone("a", [2]float64{0, 0}, [2]float64{0.5, 0.5}, [2]float64{1, 1})}, limits, []BoundaryProblemKind{BoundaryProblemSelfIntersection}, true},
+		{"bow tie", []*Boundary{zone("a", [2]float64{0, 0}, [2]float64{1, 1}, [2]float64{1, 0}, [2]float64{0, 1})}, limits, []BoundaryProblemKind{BoundaryProblemSelfIntersection}, true},
+		{"crossing line", []*Boundary{line("a", [2]float64{0, 0}, [2]float64{1, 1}, [2]float64{1, 0}, [2]float64{0, 1})}, limits, []BoundaryProblemKind{BoundaryProblemSelfIntersection}, false},
+		{"out of range", []*Boundary{line("a", [2]float64{0, 0}, [2]float64{1.5, 1})}, limits, []BoundaryProblemKind{BoundaryProblemOutOfRange}, true},
+		{"duplicate ID", []*Boundary{zone("a", square...), line("a", [2]float64{0, 0}, [2]float64{1, 1})}, limits, []BoundaryProblemKind{BoundaryProblemDuplicateID}, true},
+		{"too many zones", []*Boundary{zone("a", square...), zone("b", square...)}, limits, []BoundaryProblemKind{BoundaryProblemTooMany}, true},
+		{"too many vertices", []*Boundary{line("a", [2]float64{0, 0}, [2]float64{0.1, 0}, [2]float64{0.2, 0}, [2]float64{0.3, 0}, [2]float64{0.4, 0})}, limits, []BoundaryProblemKind{BoundaryProblemTooMany}, true},
+		{"too many zones, advisory", []*Boundary{zone("a", square...), zone("b", square...)}, advisory, []BoundaryProblemKind{BoundaryProblemTooMany}, false},
+		{"too many vertices, advisory", []*Boundary{line("a", [2]float64{0, 0}, [2]float64{0.1, 0}, [2]float64{0.2, 0}, [2]float64{0.3, 0}, [2]float64{0.4, 0})}, advisory, []BoundaryProblemKind{BoundaryProblemTooMany}, false},
+	} {
+		problems := ValidateBoundaries(tc.boundaries, tc.limits)
+		var got []BoundaryProblemKind
+		for _, p := range problems {
+			got = append(got, p.Kind)
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: got problems %v, want %v", tc.name, problems, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: got problems %v, want %v", tc.name, problems, tc.want)
+				break
+			}
+		}
+		if problems.HasErrors() != tc.wantErrors {
+			t.Errorf("%s: HasErrors() = %v, want %v", tc.name, problems.HasErrors(), tc.wantErrors)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"4.5.3-189", "4.5.3", 1},
+		{"4.5", "4.5.0", 0},
+		{"4.10", "4.9", 1},
+		{"3", "4.0", -1},
+	} {
+		if got := compareVersions(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
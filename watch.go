@@ -0,0 +1,220 @@
+package neato
+
+import (
+	"context"
+	"time"
+)
+
+// ChargeThresholds are the battery percentages that trigger a
+// StateEventChargeThreshold when crossed in either direction.
+var ChargeThresholds = []int{10, 20, 50, 80, 100}
+
+type StateEventKind int
+
+var (
+	// StateEventInitial carries the first state fetched by Watch.
+	StateEventInitial         StateEventKind = 0
+	StateEventStateChanged    StateEventKind = 1
+	StateEventActionChanged   StateEventKind = 2
+	StateEventAlertRaised     StateEventKind = 3
+	StateEventAlertCleared    StateEventKind = 4
+	StateEventErrorRaised     StateEventKind = 5
+	StateEventErrorCleared    StateEventKind = 6
+	StateEventChargeThreshold StateEventKind = 7
+	StateEventDocked          StateEventKind = 8
+	StateEventUndocked        StateEventKind = 9
+	// StateEventPollError is emitted when the state cannot be fetched.
+	StateEventPollError StateEventKind = 10
+	// StateEventPolled carries the state of a poll that found no change,
+	// so that fields like the charge percentage are up to date between
+	// events.
+	StateEventPolled StateEventKind = 11
+)
+
+func (k StateEventKind) String() string {
+	switch k {
+	case StateEventInitial:
+		return "initial"
+	case StateEventStateChanged:
+		return "state changed"
+	case StateEventActionChanged:
+		return "action changed"
+	case StateEventAlertRaised:
+		return "alert raised"
+	case StateEventAlertCleared:
+		return "alert cleared"
+	case StateEventErrorRaised:
+		return "error raised"
+	case StateEventErrorCleared:
+		return "error cleared"
+	case StateEventChargeThreshold:
+		return "charge threshold crossed"
+	case StateEventDocked:
+		return "docked"
+	case StateEventUndocked:
+		return "undocked"
+	case StateEventPollError:
+		return "poll error"
+	case StateEventPolled:
+		return "polled"
+	default:
+		return unknownEnum(int(k))
+	}
+}
+
+// StateEvent is a change between two successive robot states.
+type StateEvent struct {
+	Kind     StateEventKind
+	Time     time.Time
+	Serial   string
+	Previous *RobotState
+	Current  *RobotState
+	// Threshold is the crossed charge percentage, for
+	// StateEventChargeThreshold.
+	Threshold int
+	// Err is set for StateEventPollError.
+	Err error
+}
+
+func alertOf(s *RobotState) AlertCode {
+	if s.Alert == nil || !s.Alert.IsSet() {
+		return ""
+	}
+	return *s.Alert
+}
+
+func errorOf(s *RobotState) ErrorCode {
+	if s.Error == nil {
+		return ""
+	}
+	return *s.Error
+}
+
+// DiffStates returns the events between two successive states of a robot.
+func DiffStates(prev, cur *RobotState) []StateEvent {
+	var events []StateEvent
+	add := func(kind StateEventKind) *StateEvent {
+		events = append(events, StateEvent{Kind: kind, Previous: prev, Current: cur})
+		return &events[len(events)-1]
+	}
+	if prev.State != cur.State {
+		add(StateEventStateChanged)
+	}
+	if prev.Action != cur.Action {
+		add(StateEventActionChanged)
+	}
+	if pa, ca := alertOf(prev), alertOf(cur); pa != ca {
+		if pa != "" {
+			add(StateEventAlertCleared)
+		}
+		if ca != "" {
+			add(StateEventAlertRaised)
+		}
+	}
+	if pe, ce := errorOf(prev), errorOf(cur); pe != ce {
+		if pe != "" {
+			add(StateEventErrorCleared)
+		}
+		if ce != "" {
+			add(StateEventErrorRaised)
+		}
+	}
+	for _, t := range ChargeThresholds {
+		pc, cc := prev.Details.Charge, cur.Details.Charge
+		if (pc < t && cc >= t) || (pc >= t && cc < t) {
+			add(StateEventChargeThreshold).Threshold = t
+		}
+	}
+	if !prev.Details.IsDocked && cur.Details.IsDocked {
+		add(StateEventDocked)
+	} else if prev.Details.IsDocked && !cur.Details.IsDocked {
+		add(StateEventUndocked)
+	}
+	return events
+}
+
+// Watch polls the robot state every interval and sends an event for every
+// change on the returned channel, starting with a StateEventInitial one. A
+// non-positive interval means DefaultPollInterval.
+//
+// Besides the change events, every poll that finds no change sends a
+// StateEventPolled event with the current state. Values like the charge
+// percentage change on almost every poll without crossing a threshold, and
+// consumers that display them, like a dashboard, would otherwise have to
+// poll the robot on their own; consumers that only care about changes can
+// skip these events.
+//
+// While the robot is idle and nothing changes, the polling interval doubles
+// up to DefaultMaxPollInterval. The channel is closed when ctx is done.
+func (r *Robot) Watch(ctx context.Context, interval time.Duration) <-chan StateEvent {
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	ch := make(chan StateEvent, 16)
+	go func() {
+		defer close(ch)
+		send := func(ev StateEvent) bool {
+			ev.Serial = r.Serial
+			if ev.Time.IsZero() {
+				ev.Time = time.Now()
+			}
+			select {
+			case ch <- ev:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		var prev *RobotState
+		wait := interval
+		for {
+			state, err := r.State(ctx)
+			changed := false
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				if !send(StateEvent{Kind: StateEventPollError, Previous: prev, Err: err}) {
+					return
+				}
+			} else if prev == nil {
+				changed = true
+				if !send(StateEvent{Kind: StateEventInitial, Current: state}) {
+					return
+				}
+			} else {
+				for _, ev := range DiffStates(prev, state) {
+					changed = true
+					if !send(ev) {
+						return
+					}
+				}
+				if !changed && !send(StateEvent{Kind: StateEventPolled, Previous: prev, Current: state}) {
+					return
+				}
+			}
+			if state != nil {
+				prev = state
+			}
+
+			// back off while the robot is idle and nothing happens
+			if changed || prev == nil || prev.State != StateIdle {
+				wait = interval
+			} else {
+				wait *= 2
+				if wait > DefaultMaxPollInterval {
+					wait = DefaultMaxPollInterval
+				}
+				if wait < interval {
+					wait = interval
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,179 @@
+package neato
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffStates(t *testing.T) {
+	alert := func(a AlertCode) *AlertCode { return &a }
+	errCode := func(e ErrorCode) *ErrorCode { return &e }
+	// state builds a robot state, edited by edit.
+	state := func(edit func(s *RobotState)) *RobotState {
+		s := RobotState{State: StateIdle, Action: ActionNone}
+		s.Details.Charge = 60
+		s.Details.IsDocked = true
+		if edit != nil {
+			edit(&s)
+		}
+		return &s
+	}
+	type event struct {
+		kind      StateEventKind
+		threshold int
+	}
+	for _, tc := range []struct {
+		name      string
+		prev, cur *RobotState
+		want      []event
+	}{
+		{"no change", state(nil), state(nil), nil},
+		{
+			name: "charge change without threshold",
+			prev: state(nil),
+			cur:  state(func(s *RobotState) { s.Details.Charge = 70 }),
+		},
+		{
+			name: "start cleaning",
+			prev: state(nil),
+			cur: state(func(s *RobotState) {
+				s.State, s.Action = StateBusy, ActionHouseCleaning
+				s.Details.IsDocked = false
+			}),
+			want: []event{{kind: StateEventStateChanged}, {kind: StateEventActionChanged}, {kind: StateEventUndocked}},
+		},
+		{
+			name: "back on base",
+			prev: state(func(s *RobotState) { s.Action = ActionDocking; s.Details.IsDocked = false }),
+			cur:  state(nil),
+			want: []event{{kind: StateEventActionChanged}, {kind: StateEventDocked}},
+		},
+		{
+			name: "alert raised",
+			prev: state(func(s *RobotState) { s.Alert = alert("ui_alert_invalid") }),
+			cur:  state(func(s *RobotState) { s.Alert = alert("dustbin_full") }),
+			want: []event{{kind: StateEventAlertRaised}},
+		},
+		{
+			name: "alert replaced",
+			prev: state(func(s *RobotState) { s.Alert = alert("dustbin_full") }),
+			cur:  state(func(s *RobotState) { s.Alert = alert("maint_brush_change") }),
+			want: []event{{kind: StateEventAlertCleared}, {kind: StateEventAlertRaised}},
+		},
+		{
+			name: "alert cleared",
+			prev: state(func(s *RobotState) { s.Alert = alert("dustbin_full") }),
+			cur:  state(nil),
+			want: []event{{kind: StateEventAlertCleared}},
+		},
+		{
+			name: "error raised",
+			prev: state(nil),
+			cur: state(func(s *RobotState) {
+				s.State = StateError
+				s.Error = errCode("ui_error_brush_stuck")
+			}),
+			want: []event{{kind: StateEventStateChanged}, {kind: StateEventErrorRaised}},
+		},
+		{
+			name: "error cleared",
+			prev: state(func(s *RobotState) { s.Error = errCode("ui_error_brush_stuck") }),
+			cur:  state(func(s *RobotState) { s.Error = errCode("") }),
+			want: []event{{kind: StateEventErrorCleared}},
+		},
+		{
+			name: "charge drops below thresholds",
+			prev: state(func(s *RobotState) { s.Details.Charge = 55 }),
+			cur:  state(func(s *RobotState) { s.Details.Charge = 15 }),
+			want: []event{{StateEventChargeThreshold, 20}, {StateEventChargeThreshold, 50}},
+		},
+		{
+			name: "charge reaches a threshold",
+			prev: state(func(s *RobotState) { s.Details.Charge = 99 }),
+			cur:  state(func(s *RobotState) { s.Details.Charge = 100 }),
+			want: []event{{StateEventChargeThreshold, 100}},
+		},
+	} {
+		var got []event
+		for _, ev := range DiffStates(tc.prev, tc.cur) {
+			if ev.Previous != tc.prev || ev.Current != tc.cur {
+				t.Errorf("%s: %s event does not carry both states", tc.name, ev.Kind)
+			}
+			got = append(got, event{ev.Kind, ev.Threshold})
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got events %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	f := newFakeCloud(t)
+	robots, err := f.account(nil).Robots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := robots[0].Watch(ctx, 10*time.Millisecond)
+
+	// next returns the next event that is not of kind skip.
+	next := func(skip StateEventKind) StateEvent {
+		t.Helper()
+		for {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					t.Fatal("events channel closed")
+				}
+				if ev.Serial != f.serial || ev.Time.IsZero() {
+					t.Errorf("got event %+v without serial or time", ev)
+				}
+				if ev.Kind != skip {
+					return ev
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for an event")
+			}
+		}
+	}
+	if ev := next(-1); ev.Kind != StateEventInitial || ev.Current == nil {
+		t.Fatalf("got first event %s, want %s with a state", ev.Kind, StateEventInitial)
+	}
+	if ev := next(-1); ev.Kind != StateEventPolled || ev.Current == nil {
+		t.Fatalf("got event %s, want %s with a state", ev.Kind, StateEventPolled)
+	}
+	f.setState("state", int(StateBusy))
+	if ev := next(StateEventPolled); ev.Kind != StateEventStateChanged || ev.Current.State != StateBusy {
+		t.Fatalf("got event %s, want %s to busy", ev.Kind, StateEventStateChanged)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchDefaultInterval(t *testing.T) {
+	f := newFakeCloud(t)
+	robots, err := f.account(nil).Robots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// a non-positive interval does not make Watch spin or fail
+	events := robots[0].Watch(ctx, 0)
+	select {
+	case ev := <-events:
+		if ev.Kind != StateEventInitial {
+			t.Errorf("got first event %s, want %s", ev.Kind, StateEventInitial)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the initial event")
+	}
+	cancel()
+	for range events {
+	}
+}